@@ -0,0 +1,315 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"slices"
+)
+
+// Layout of the binary snapshot written by WriteTo:
+//
+//	magic    "SSET"
+//	version  1 byte
+//	count    uvarint
+//	count records in ascending (score, key) order, each made of
+//	         uvarint length + encoded key
+//	         uvarint length + encoded score
+//	         uvarint length + encoded value
+//	checksum 4 bytes, little endian CRC-32C of all the previous bytes
+const (
+	snapshotMagic   = "SSET"
+	snapshotVersion = 1
+	// an encoded field longer than this is treated as corruption
+	snapshotMaxFieldLength = 1 << 30
+	// a field is read in chunks of this size, so that a corrupted length
+	// cannot allocate much more memory than the data it is followed by
+	snapshotReadChunk = 64 << 10
+)
+
+var (
+	// ErrSnapshotFormat is returned when the data is not a snapshot of a supported version
+	ErrSnapshotFormat = errors.New("sortedset: invalid snapshot format")
+	// ErrSnapshotChecksum is returned when the checksum of a snapshot does not match its content
+	ErrSnapshotChecksum = errors.New("sortedset: snapshot checksum mismatch")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// SetCodecs sets the codecs used by WriteTo, ReadFrom, MarshalBinary and UnmarshalBinary
+func (this *SortedSet[K, SCORE, V]) SetCodecs(codecs Codecs[K, SCORE, V]) {
+	this.codecs = codecs
+}
+
+func (this *SortedSet[K, SCORE, V]) keyCodec() Codec[K] {
+	if this.codecs.Key != nil {
+		return this.codecs.Key
+	}
+	return DefaultCodec[K]()
+}
+
+func (this *SortedSet[K, SCORE, V]) scoreCodec() Codec[SCORE] {
+	if this.codecs.Score != nil {
+		return this.codecs.Score
+	}
+	return DefaultCodec[SCORE]()
+}
+
+func (this *SortedSet[K, SCORE, V]) valueCodec() Codec[V] {
+	if this.codecs.Value != nil {
+		return this.codecs.Value
+	}
+	return DefaultCodec[V]()
+}
+
+// appendField appends a length-prefixed value encoded by codec
+func appendField[T any](buf []byte, scratch []byte, codec Codec[T], value T) ([]byte, []byte, error) {
+	scratch, err := codec.Append(scratch[:0], value)
+	if err != nil {
+		return buf, scratch, err
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(scratch)))
+	return append(buf, scratch...), scratch, nil
+}
+
+// streamCodecs returns the codecs of one snapshot stream: the configured
+// codecs, or a new gobStreamCodec for each field
+func (this *SortedSet[K, SCORE, V]) streamCodecs() (Codec[K], Codec[SCORE], Codec[V]) {
+	return streamCodec(this.codecs.Key), streamCodec(this.codecs.Score), streamCodec(this.codecs.Value)
+}
+
+// WriteTo writes a binary snapshot of the set to w and returns the number of bytes written.
+//
+// Time complexity of this method is : O(N)
+func (this *SortedSet[K, SCORE, V]) WriteTo(w io.Writer) (int64, error) {
+	now := this.expiredNow()
+	keyCodec, scoreCodec, valueCodec := this.streamCodecs()
+	crc := crc32.New(castagnoli)
+	writer := bufio.NewWriter(io.MultiWriter(w, crc))
+
+	buf := append([]byte(snapshotMagic), snapshotVersion)
+	buf = binary.AppendUvarint(buf, uint64(this.liveLength(now)))
+	written, err := writer.Write(buf)
+	total := int64(written)
+	if err != nil {
+		return total, err
+	}
+
+	var scratch []byte
+	for x := this.firstAlive(this.header.level[0].forward, now); x != nil; x = this.firstAlive(x.level[0].forward, now) {
+		buf = buf[:0]
+		if buf, scratch, err = appendField(buf, scratch, keyCodec, x.key); err != nil {
+			return total, err
+		}
+		if buf, scratch, err = appendField(buf, scratch, scoreCodec, x.score); err != nil {
+			return total, err
+		}
+		if buf, scratch, err = appendField(buf, scratch, valueCodec, x.Value); err != nil {
+			return total, err
+		}
+		written, err = writer.Write(buf)
+		total += int64(written)
+		if err != nil {
+			return total, err
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		return total, err
+	}
+
+	written, err = w.Write(binary.LittleEndian.AppendUint32(nil, crc.Sum32()))
+	return total + int64(written), err
+}
+
+// byteReader is the reader of a snapshot, see ReadFrom
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// snapshotReader reads a snapshot while updating its checksum
+type snapshotReader struct {
+	reader byteReader
+	crc    hash.Hash32
+	read   int64
+	buf    []byte
+}
+
+func (this *snapshotReader) ReadByte() (byte, error) {
+	b, err := this.reader.ReadByte()
+	if err == nil {
+		this.crc.Write([]byte{b})
+		this.read++
+	}
+	return b, err
+}
+
+// next returns the next n bytes, valid until the next call
+//
+// The buffer grows with the data actually read, not with n.
+func (this *snapshotReader) next(n int) ([]byte, error) {
+	this.buf = this.buf[:0]
+	for len(this.buf) < n {
+		offset := len(this.buf)
+		chunk := min(n-offset, snapshotReadChunk)
+		this.buf = slices.Grow(this.buf, chunk)[:offset+chunk]
+		read, err := io.ReadFull(this.reader, this.buf[offset:])
+		this.read += int64(read)
+		this.crc.Write(this.buf[offset : offset+read])
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return this.buf[:offset+read], err
+		}
+	}
+	return this.buf, nil
+}
+
+func (this *snapshotReader) uvarint() (uint64, error) {
+	v, err := binary.ReadUvarint(this)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return v, err
+}
+
+// readField reads a length-prefixed value and decodes it with codec
+func readField[T any](reader *snapshotReader, codec Codec[T]) (T, error) {
+	var value T
+	length, err := reader.uvarint()
+	if err != nil {
+		return value, err
+	}
+	if length > snapshotMaxFieldLength {
+		return value, ErrSnapshotFormat
+	}
+	data, err := reader.next(int(length))
+	if err != nil {
+		return value, err
+	}
+	return codec.Decode(data)
+}
+
+// ReadFrom replaces the content of the set with a binary snapshot read from r
+// and returns the number of bytes read.
+//
+// If r implements io.ByteReader, like *bufio.Reader and *bytes.Reader, no byte
+// past the end of the snapshot is consumed, so r can be read further.
+// Otherwise r is wrapped in a bufio.Reader, which may consume bytes of r
+// following the snapshot.
+//
+// The records are already sorted, so the skip list is rebuilt in a single pass
+// without searching. If an error is returned, the set is left empty.
+//
+// Time complexity of this method is : O(N)
+func (this *SortedSet[K, SCORE, V]) ReadFrom(r io.Reader) (int64, error) {
+	reader := &snapshotReader{crc: crc32.New(castagnoli)}
+	if br, ok := r.(byteReader); ok {
+		reader.reader = br
+	} else {
+		reader.reader = bufio.NewReader(r)
+	}
+	this.reset()
+	err := this.readSnapshot(reader)
+	if err != nil {
+		this.reset()
+	}
+	return reader.read, err
+}
+
+func (this *SortedSet[K, SCORE, V]) readSnapshot(reader *snapshotReader) error {
+	header, err := reader.next(len(snapshotMagic) + 1)
+	if err != nil {
+		return err
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic || header[len(snapshotMagic)] != snapshotVersion {
+		return ErrSnapshotFormat
+	}
+	count, err := reader.uvarint()
+	if err != nil {
+		return err
+	}
+
+	keyCodec, scoreCodec, valueCodec := this.streamCodecs()
+	builder := newSortedSetBuilder(this)
+	for i := uint64(0); i < count; i++ {
+		key, err := readField(reader, keyCodec)
+		if err != nil {
+			return err
+		}
+		score, err := readField(reader, scoreCodec)
+		if err != nil {
+			return err
+		}
+		value, err := readField(reader, valueCodec)
+		if err != nil {
+			return err
+		}
+		if tail := this.tail; tail != nil && this.compare(tail, score, key) >= 0 {
+			return ErrSnapshotFormat // out of order
+		}
+		if this.lookup(key) != nil {
+			return ErrSnapshotFormat // duplicated key
+		}
+		builder.append(score, key, value)
+	}
+	builder.finish()
+
+	sum := reader.crc.Sum32()
+	trailer, err := reader.next(4)
+	if err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint32(trailer) != sum {
+		return ErrSnapshotChecksum
+	}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler with the format of WriteTo
+func (this *SortedSet[K, SCORE, V]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := this.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler with the format of ReadFrom
+func (this *SortedSet[K, SCORE, V]) UnmarshalBinary(data []byte) error {
+	read, err := this.ReadFrom(bytes.NewReader(data))
+	if err == nil && read != int64(len(data)) {
+		this.reset()
+		err = ErrSnapshotFormat
+	}
+	return err
+}
@@ -0,0 +1,209 @@
+package sortedset
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*SortedSet[string, int64, string])(nil)
+	_ encoding.BinaryUnmarshaler = (*SortedSet[string, int64, string])(nil)
+	_ io.WriterTo                = (*SortedSet[string, int64, string])(nil)
+	_ io.ReaderFrom              = (*SortedSet[string, int64, string])(nil)
+)
+
+type player struct {
+	Name  string
+	Level int
+}
+
+// decimalCodec stores integers as decimal text
+type decimalCodec struct{}
+
+func (decimalCodec) Append(buf []byte, value int64) ([]byte, error) {
+	return strconv.AppendInt(buf, value, 10), nil
+}
+
+func (decimalCodec) Decode(data []byte) (int64, error) {
+	return strconv.ParseInt(string(data), 10, 64)
+}
+
+func TestBinarySnapshot(t *testing.T) {
+	sortedset := New[string, int64, string]()
+	sortedset.AddOrUpdate("a", 89, "Kelly")
+	sortedset.AddOrUpdate("b", 100, "Staley")
+	sortedset.AddOrUpdate("c", 100, "Jordon")
+	sortedset.AddOrUpdate("d", -321, "Park")
+	sortedset.AddOrUpdate("h", 70, "Audrey")
+
+	data, err := sortedset.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded SortedSet[string, int64, string]
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, loaded.GetRangeByRank(1, -1, false), []string{"d", "h", "a", "b", "c"})
+	if node := loaded.GetByKey("b"); node == nil || node.Score() != 100 || node.Value != "Staley" {
+		t.Errorf("GetByKey() returned %v", node)
+	}
+	if rank := loaded.FindRank("a"); rank != 3 {
+		t.Errorf("FindRank() returned %d, expected 3", rank)
+	}
+	loaded.AddOrUpdate("e", 95, "Albert")
+	checkOrder(t, loaded.GetRangeByRank(3, -1, false), []string{"a", "e", "b", "c"})
+
+	// any flipped byte is detected
+	for i := range data {
+		corrupted := bytes.Clone(data)
+		corrupted[i] ^= 0x40
+		if err := loaded.UnmarshalBinary(corrupted); err == nil {
+			t.Errorf("UnmarshalBinary() accepted a corrupted byte %d", i)
+		}
+	}
+	if loaded.GetCount() != 0 {
+		t.Error("a failed UnmarshalBinary() did not leave the set empty")
+	}
+
+	if err := loaded.UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("UnmarshalBinary() of a truncated snapshot returned %v", err)
+	}
+	if err := loaded.UnmarshalBinary(append(bytes.Clone(data), 0)); err != ErrSnapshotFormat {
+		t.Errorf("UnmarshalBinary() with trailing data returned %v", err)
+	}
+	if err := loaded.UnmarshalBinary([]byte("ZSET\x01\x00")); err != ErrSnapshotFormat {
+		t.Errorf("UnmarshalBinary() with a bad magic returned %v", err)
+	}
+
+	var empty SortedSet[string, int64, string]
+	data, _ = New[string, int64, string]().MarshalBinary()
+	if err := empty.UnmarshalBinary(data); err != nil || empty.GetCount() != 0 || empty.PeekMin() != nil {
+		t.Errorf("UnmarshalBinary() of an empty set returned %v", err)
+	}
+}
+
+func TestBinarySnapshotCodecs(t *testing.T) {
+	sortedset := New[int, float64, player]()
+	for i := 0; i < 1000; i++ {
+		sortedset.AddOrUpdate(i, float64(i%10)/4, player{Name: strconv.Itoa(i), Level: i})
+	}
+
+	var buf bytes.Buffer
+	written, err := sortedset.WriteTo(&buf)
+	if err != nil || written != int64(buf.Len()) {
+		t.Fatalf("WriteTo() returned %d, %v for %d bytes", written, err, buf.Len())
+	}
+	loaded := New[int, float64, player]()
+	if read, err := loaded.ReadFrom(&buf); err != nil || read != written {
+		t.Fatalf("ReadFrom() returned %d, %v", read, err)
+	}
+	if loaded.GetCount() != 1000 || loaded.GetByKey(123).Value != (player{Name: "123", Level: 123}) {
+		t.Error("ReadFrom() did not restore the nodes")
+	}
+	if nodes := loaded.GetRangeByScore(0.5, 0.5, nil); len(nodes) != 100 || nodes[0].Key() != 2 {
+		t.Error("ReadFrom() did not restore the order")
+	}
+
+	custom := New[string, int64, string]()
+	custom.SetCodecs(Codecs[string, int64, string]{Score: decimalCodec{}})
+	custom.AddOrUpdate("a", 12345, "x")
+	data, _ := custom.MarshalBinary()
+	if !bytes.Contains(data, []byte("12345")) {
+		t.Error("MarshalBinary() did not use the score codec")
+	}
+	restored := New[string, int64, string]()
+	restored.SetCodecs(Codecs[string, int64, string]{Score: decimalCodec{}})
+	if err := restored.UnmarshalBinary(data); err != nil || restored.GetByKey("a").Score() != 12345 {
+		t.Errorf("UnmarshalBinary() returned %v", err)
+	}
+}
+
+// appendTestSnapshot appends a snapshot of string keys, int64 scores and
+// string values in the given record order, with a valid checksum
+func appendTestSnapshot(data []byte, records ...any) []byte {
+	start := len(data)
+	data = append(data, snapshotMagic...)
+	data = append(data, snapshotVersion)
+	data = binary.AppendUvarint(data, uint64(len(records)/2))
+	for i := 0; i < len(records); i += 2 {
+		key, score := records[i].(string), int64(records[i+1].(int))
+		data, _, _ = appendField(data, nil, DefaultCodec[string](), key)
+		data, _, _ = appendField(data, nil, DefaultCodec[int64](), score)
+		data, _, _ = appendField(data, nil, DefaultCodec[string](), "")
+	}
+	return binary.LittleEndian.AppendUint32(data, crc32.Checksum(data[start:], castagnoli))
+}
+
+func TestBinarySnapshotDuplicateKey(t *testing.T) {
+	var loaded SortedSet[string, int64, string]
+	if err := loaded.UnmarshalBinary(appendTestSnapshot(nil, "a", 1, "b", 2, "c", 3)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the duplicated key is not adjacent, so the records are in order
+	err := loaded.UnmarshalBinary(appendTestSnapshot(nil, "a", 1, "b", 2, "a", 3))
+	if err != ErrSnapshotFormat {
+		t.Errorf("UnmarshalBinary() of a duplicated key returned %v", err)
+	}
+	if loaded.GetCount() != 0 || loaded.Validate() != nil {
+		t.Error("a failed UnmarshalBinary() did not leave the set empty")
+	}
+}
+
+func TestBinarySnapshotStream(t *testing.T) {
+	data := appendTestSnapshot(nil, "a", 1, "b", 2)
+	data = appendTestSnapshot(data, "c", 3)
+
+	// a byte reader is not read past the end of each snapshot
+	reader := bytes.NewReader(data)
+	first, second := New[string, int64, string](), New[string, int64, string]()
+	if _, err := first.ReadFrom(reader); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.ReadFrom(reader); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, first.GetRangeByRank(1, -1, false), []string{"a", "b"})
+	checkOrder(t, second.GetRangeByRank(1, -1, false), []string{"c"})
+
+	// a huge field length is not allocated before the data is read
+	data = append([]byte(snapshotMagic), snapshotVersion, 1)
+	data = binary.AppendUvarint(data, snapshotMaxFieldLength)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if err := first.UnmarshalBinary(data); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("UnmarshalBinary() of a truncated field returned %v", err)
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("UnmarshalBinary() of a truncated field allocated %d bytes", allocated)
+	}
+}
+
+func TestBinarySnapshotGobStream(t *testing.T) {
+	sortedset := New[int, float64, player]()
+	for i := 0; i < 100; i++ {
+		sortedset.AddOrUpdate(i, float64(i), player{Name: strconv.Itoa(i), Level: i})
+	}
+	data, err := sortedset.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the gob type information is written once for the whole snapshot
+	if count := bytes.Count(data, []byte("Level")); count != 1 {
+		t.Errorf("the snapshot holds %d definitions of the value type", count)
+	}
+	loaded := New[int, float64, player]()
+	if err := loaded.UnmarshalBinary(data); err != nil || loaded.GetByKey(99).Value != (player{Name: "99", Level: 99}) {
+		t.Errorf("UnmarshalBinary() returned %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrRangeBound is returned by ParseScoreBound and ParseLexBound for a malformed bound
+var ErrRangeBound = errors.New("sortedset: invalid range bound")
+
+// ScoreBound is an endpoint of a range of scores, like the "1.5", "(1.5",
+// "-inf" and "+inf" arguments of the Redis ZRANGEBYSCORE command
+type ScoreBound[SCORE any] struct {
+	Score SCORE // ignored for BoundNegInf and BoundPosInf
+	Kind  BoundKind
+}
+
+// ScoreInclusive returns the bound score
+func ScoreInclusive[SCORE any](score SCORE) ScoreBound[SCORE] {
+	return ScoreBound[SCORE]{Score: score, Kind: BoundInclusive}
+}
+
+// ScoreExclusive returns the bound (score
+func ScoreExclusive[SCORE any](score SCORE) ScoreBound[SCORE] {
+	return ScoreBound[SCORE]{Score: score, Kind: BoundExclusive}
+}
+
+// ScoreNegInf returns the bound -inf, before every score
+func ScoreNegInf[SCORE any]() ScoreBound[SCORE] {
+	return ScoreBound[SCORE]{Kind: BoundNegInf}
+}
+
+// ScorePosInf returns the bound +inf, after every score
+func ScorePosInf[SCORE any]() ScoreBound[SCORE] {
+	return ScoreBound[SCORE]{Kind: BoundPosInf}
+}
+
+// after reports whether this bound is positioned after other
+func (this ScoreBound[SCORE]) after(compare func(a, b SCORE) int, other ScoreBound[SCORE]) bool {
+	if this.Kind == BoundNegInf || other.Kind == BoundPosInf {
+		return false
+	}
+	if this.Kind == BoundPosInf || other.Kind == BoundNegInf {
+		return true
+	}
+	return compare(this.Score, other.Score) > 0
+}
+
+// gteMin reports whether score is on the right side of the min bound
+func (this ScoreBound[SCORE]) gteMin(compare func(a, b SCORE) int, score SCORE) bool {
+	switch this.Kind {
+	case BoundNegInf:
+		return true
+	case BoundPosInf:
+		return false
+	case BoundExclusive:
+		return compare(score, this.Score) > 0
+	}
+	return compare(score, this.Score) >= 0
+}
+
+// lteMax reports whether score is on the left side of the max bound
+func (this ScoreBound[SCORE]) lteMax(compare func(a, b SCORE) int, score SCORE) bool {
+	switch this.Kind {
+	case BoundNegInf:
+		return false
+	case BoundPosInf:
+		return true
+	case BoundExclusive:
+		return compare(score, this.Score) < 0
+	}
+	return compare(score, this.Score) <= 0
+}
+
+// exclude turns an inclusive bound into an exclusive one when exclude is true
+func (this ScoreBound[SCORE]) exclude(exclude bool) ScoreBound[SCORE] {
+	if exclude && this.Kind == BoundInclusive {
+		this.Kind = BoundExclusive
+	}
+	return this
+}
+
+// applyExcludes applies ExcludeStart and ExcludeEnd of options to start and end
+func applyExcludes[SCORE any](start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions) (ScoreBound[SCORE], ScoreBound[SCORE]) {
+	if options == nil {
+		return start, end
+	}
+	return start.exclude(options.ExcludeStart), end.exclude(options.ExcludeEnd)
+}
+
+// ParseScoreBound parses a score bound in the Redis syntax: "-inf", "+inf"
+// (or "inf"), a score, or a score prefixed with "(" to exclude it. The score
+// itself is parsed by parse.
+//
+// Errors of parse are joined with ErrRangeBound.
+func ParseScoreBound[SCORE any](s string, parse func(s string) (SCORE, error)) (ScoreBound[SCORE], error) {
+	kind := BoundInclusive
+	if strings.HasPrefix(s, "(") {
+		s, kind = s[1:], BoundExclusive
+	}
+	switch strings.ToLower(s) {
+	case "-inf":
+		return ScoreNegInf[SCORE](), nil
+	case "+inf", "inf":
+		return ScorePosInf[SCORE](), nil
+	}
+	score, err := parse(s)
+	if err != nil {
+		return ScoreBound[SCORE]{}, errors.Join(ErrRangeBound, err)
+	}
+	return ScoreBound[SCORE]{Score: score, Kind: kind}, nil
+}
+
+// ParseLexBound parses a key bound in the Redis syntax: "-", "+", or a key
+// prefixed with "[" to include it or "(" to exclude it. The key itself is
+// parsed by parse.
+//
+// Errors of parse are joined with ErrRangeBound.
+func ParseLexBound[K any](s string, parse func(s string) (K, error)) (LexBound[K], error) {
+	var kind BoundKind
+	switch {
+	case s == "-":
+		return LexNegInf[K](), nil
+	case s == "+":
+		return LexPosInf[K](), nil
+	case strings.HasPrefix(s, "["):
+		kind = BoundInclusive
+	case strings.HasPrefix(s, "("):
+		kind = BoundExclusive
+	default:
+		return LexBound[K]{}, ErrRangeBound
+	}
+	key, err := parse(s[1:])
+	if err != nil {
+		return LexBound[K]{}, errors.Join(ErrRangeBound, err)
+	}
+	return LexBound[K]{Key: key, Kind: kind}, nil
+}
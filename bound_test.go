@@ -0,0 +1,91 @@
+package sortedset
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func parseInt64(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func TestParseScoreBound(t *testing.T) {
+	for s, expected := range map[string]ScoreBound[int64]{
+		"10":    ScoreInclusive[int64](10),
+		"(-5":   ScoreExclusive[int64](-5),
+		"-inf":  ScoreNegInf[int64](),
+		"+inf":  ScorePosInf[int64](),
+		"inf":   ScorePosInf[int64](),
+		"(+INF": ScorePosInf[int64](),
+	} {
+		if bound, err := ParseScoreBound(s, parseInt64); err != nil || bound != expected {
+			t.Errorf("ParseScoreBound(%q) returned %+v, %v, expected %+v", s, bound, err, expected)
+		}
+	}
+	for _, s := range []string{"", "(", "1.5", "[1"} {
+		if _, err := ParseScoreBound(s, parseInt64); !errors.Is(err, ErrRangeBound) {
+			t.Errorf("ParseScoreBound(%q) returned %v, expected ErrRangeBound", s, err)
+		}
+	}
+}
+
+func TestParseLexBound(t *testing.T) {
+	identity := func(s string) (string, error) { return s, nil }
+	for s, expected := range map[string]LexBound[string]{
+		"[a": LexInclusive("a"),
+		"(a": LexExclusive("a"),
+		"[":  LexInclusive(""),
+		"-":  LexNegInf[string](),
+		"+":  LexPosInf[string](),
+	} {
+		if bound, err := ParseLexBound(s, identity); err != nil || bound != expected {
+			t.Errorf("ParseLexBound(%q) returned %+v, %v, expected %+v", s, bound, err, expected)
+		}
+	}
+	for _, s := range []string{"", "a", "-a"} {
+		if _, err := ParseLexBound(s, identity); !errors.Is(err, ErrRangeBound) {
+			t.Errorf("ParseLexBound(%q) returned %v, expected ErrRangeBound", s, err)
+		}
+	}
+	if _, err := ParseLexBound("[x", parseInt64); !errors.Is(err, ErrRangeBound) {
+		t.Errorf("ParseLexBound() returned %v, expected ErrRangeBound", err)
+	}
+}
+
+func TestGetRangeByScoreBound(t *testing.T) {
+	sortedset := New[string, int64, string]()
+	sortedset.AddOrUpdate("a", 89, "Kelly")
+	sortedset.AddOrUpdate("b", 100, "Staley")
+	sortedset.AddOrUpdate("c", 100, "Jordon")
+	sortedset.AddOrUpdate("d", -321, "Park")
+	sortedset.AddOrUpdate("e", 101, "Albert")
+
+	negInf, posInf := ScoreNegInf[int64](), ScorePosInf[int64]()
+	checkOrder(t, sortedset.GetRangeByScoreBound(negInf, posInf, nil), []string{"d", "a", "b", "c", "e"})
+	checkOrder(t, sortedset.GetRangeByScoreBound(posInf, negInf, &GetRangeByScoreOptions{Limit: 2}), []string{"e", "c"})
+	checkOrder(t, sortedset.GetRangeByScoreBound(ScoreExclusive[int64](89), posInf, nil), []string{"b", "c", "e"})
+	checkOrder(t, sortedset.GetRangeByScoreBound(ScoreExclusive[int64](100), ScoreInclusive[int64](0), nil), []string{"a"})
+	checkOrder(t, sortedset.GetRangeByScoreBound(ScoreInclusive[int64](100), posInf, &GetRangeByScoreOptions{ExcludeStart: true}), []string{"e"})
+	checkOrder(t, sortedset.GetRangeByScoreBound(posInf, posInf, nil), nil)
+	checkOrder(t, sortedset.GetRangeByScoreBound(ScoreExclusive[int64](100), ScoreExclusive[int64](100), nil), nil)
+
+	var keys []string
+	for key := range sortedset.RangeByScoreBound(ScoreInclusive[int64](100), negInf, nil) {
+		keys = append(keys, key)
+	}
+	if len(keys) != 4 || keys[0] != "c" || keys[3] != "d" {
+		t.Errorf("RangeByScoreBound() yielded %v", keys)
+	}
+
+	if count := sortedset.CountByScoreBound(negInf, ScoreExclusive[int64](100), nil); count != 2 {
+		t.Errorf("CountByScoreBound() returned %d, expected 2", count)
+	}
+	if count := sortedset.CountByScoreBound(posInf, ScoreInclusive[int64](100), nil); count != 3 {
+		t.Errorf("CountByScoreBound() returned %d, expected 3", count)
+	}
+	if removed := sortedset.RemoveRangeByScoreBound(ScoreExclusive[int64](89), posInf, &GetRangeByScoreOptions{Limit: 2}, nil); removed != 2 {
+		t.Errorf("RemoveRangeByScoreBound() removed %d nodes, expected 2", removed)
+	}
+	checkOrder(t, sortedset.GetRangeByRank(1, -1, false), []string{"d", "a", "e"})
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+// EvictPolicy tells which end of a full set is evicted by a new element
+type EvictPolicy int
+
+const (
+	EvictLowest  EvictPolicy = iota // evict the element with the lowest score, keeping the top scores
+	EvictHighest                    // evict the element with the highest score, keeping the bottom scores
+)
+
+// SetCapacity bounds the number of elements of the set, like a top-K structure.
+//
+// When the set holds capacity elements, a new element evicts the element at
+// the end chosen by policy, and onEvict, if not nil, is called with the
+// evicted node after it is removed. A new element that would be evicted at
+// once is rejected without allocating a node: AddOrUpdate returns false and
+// Add returns 0. Updates of existing elements are never rejected.
+//
+// If the set holds more than capacity elements, the extra ones are evicted
+// at once. A capacity that is not positive removes the bound. Expired elements
+// are not counted.
+//
+// Time complexity of this method is : O(M*log(N)) for M evicted elements
+func (this *SortedSet[K, SCORE, V]) SetCapacity(capacity int, policy EvictPolicy, onEvict func(node *SortedSetNode[K, SCORE, V])) {
+	this.capacity = max(capacity, 0)
+	this.evictPolicy = policy
+	this.onEvict = onEvict
+	this.trim()
+}
+
+// admit reports whether a new element at (score, key) makes the cut of a bounded set
+func (this *SortedSet[K, SCORE, V]) admit(score SCORE, key K) bool {
+	if this.capacity == 0 {
+		return true
+	}
+	now := this.expiredNow()
+	if this.liveLength(now) < this.capacity {
+		return true
+	}
+	if this.evictPolicy == EvictHighest {
+		return this.compare(this.lastAlive(this.tail, now), score, key) > 0
+	}
+	return this.compare(this.firstAlive(this.header.level[0].forward, now), score, key) < 0
+}
+
+// trim evicts elements until the set is within its capacity
+func (this *SortedSet[K, SCORE, V]) trim() {
+	for this.capacity > 0 && this.length > int64(this.capacity) {
+		now := this.expiredNow()
+		if this.liveLength(now) <= this.capacity {
+			return
+		}
+		x := this.firstAlive(this.header.level[0].forward, now)
+		if this.evictPolicy == EvictHighest {
+			x = this.lastAlive(this.tail, now)
+		}
+		this.delete(x.score, x.key)
+		if this.onEvict != nil {
+			this.onEvict(x)
+		}
+	}
+}
+
+// insert adds a new element unless the capacity rejects it, evicting another
+// element if the set is full. It returns nil if the element is rejected.
+func (this *SortedSet[K, SCORE, V]) insert(score SCORE, key K, value V) *SortedSetNode[K, SCORE, V] {
+	if !this.admit(score, key) {
+		return nil
+	}
+	x := this.insertNode(score, key, value)
+	this.dict.Store(key, x)
+	this.trim()
+	return x
+}
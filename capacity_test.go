@@ -0,0 +1,100 @@
+package sortedset
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSetCapacity(t *testing.T) {
+	sortedset := New[string, int, int]()
+	var evicted []string
+	sortedset.SetCapacity(3, EvictLowest, func(node *SortedSetNode[string, int, int]) {
+		evicted = append(evicted, node.Key())
+	})
+
+	for i, key := range []string{"a", "b", "c"} {
+		sortedset.AddOrUpdate(key, (i+1)*10, 0)
+	}
+	// a new top score evicts the lowest one
+	if !sortedset.AddOrUpdate("d", 40, 0) {
+		t.Error("AddOrUpdate() of a top score returned false")
+	}
+	// a score below the cut is rejected
+	if sortedset.AddOrUpdate("e", 5, 0) || sortedset.Has("e") {
+		t.Error("AddOrUpdate() below the cut was not rejected")
+	}
+	// a tie with the lowest score is ordered by key
+	if sortedset.AddOrUpdate("a", 20, 0) || sortedset.Has("a") {
+		t.Error("AddOrUpdate() ordered before the lowest node was not rejected")
+	}
+	if n, _ := sortedset.Add("bb", 20, 0, nil); n != 1 {
+		t.Error("Add() ordered after the lowest node was rejected")
+	}
+	// updates are never rejected
+	sortedset.AddOrUpdate("d", 1, 0)
+	if score, ok, _ := AddIncr(sortedset, "x", 0, 0, nil); ok || score != 0 {
+		t.Errorf("AddIncr() below the cut returned %v, %v", score, ok)
+	}
+	if score, ok, _ := IncrementScore(sortedset, "x", 0); ok || score != 0 || sortedset.Has("x") {
+		t.Errorf("IncrementScore() below the cut returned %v, %v", score, ok)
+	}
+	IncrementScore(sortedset, "y", 100)
+
+	expectKeys := func(expected string) {
+		t.Helper()
+		var keys []string
+		for key := range sortedset.All() {
+			keys = append(keys, key)
+		}
+		if fmt.Sprint(keys) != expected {
+			t.Errorf("keys are %v, expected %s", keys, expected)
+		}
+		if err := sortedset.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+	expectKeys("[bb c y]")
+	if fmt.Sprint(evicted) != "[a b d]" {
+		t.Errorf("evicted keys are %v", evicted)
+	}
+
+	// shrinking the capacity evicts at once, from the highest end with EvictHighest
+	evicted = nil
+	sortedset.SetCapacity(1, EvictHighest, func(node *SortedSetNode[string, int, int]) {
+		evicted = append(evicted, node.Key())
+	})
+	expectKeys("[bb]")
+	if fmt.Sprint(evicted) != "[y c]" {
+		t.Errorf("evicted keys are %v", evicted)
+	}
+	if sortedset.AddOrUpdate("z", 30, 0) || !sortedset.AddOrUpdate("w", 0, 0) {
+		t.Error("EvictHighest admitted the wrong element")
+	}
+	expectKeys("[w]")
+
+	// without capacity, the set grows again
+	sortedset.SetCapacity(0, EvictLowest, nil)
+	sortedset.AddOrUpdate("z", 30, 0)
+	expectKeys("[w z]")
+
+	// loading a set keeps the capacity
+	sortedset.SetCapacity(2, EvictLowest, nil)
+	if err := sortedset.UnmarshalJSON([]byte(`[{"key":"a","score":1},{"key":"b","score":2},{"key":"c","score":3}]`)); err != nil {
+		t.Fatal(err)
+	}
+	expectKeys("[b c]")
+}
+
+func TestSetCapacityRejectWithoutAllocation(t *testing.T) {
+	sortedset := New[int, int, int]()
+	sortedset.SetCapacity(100, EvictLowest, nil)
+	for i := 0; i < 100; i++ {
+		sortedset.AddOrUpdate(i, 1000+i, i)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		sortedset.AddOrUpdate(100000, 1, 0)
+	})
+	if allocs != 0 {
+		t.Errorf("a rejected insert allocated %v times", allocs)
+	}
+}
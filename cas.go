@@ -0,0 +1,561 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"cmp"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/exp/constraints"
+)
+
+// number of mutexes serializing writers of the same key
+const casKeyLocks = 64
+
+// casRef is an immutable (forward pointer, deletion mark) pair. A node is
+// logically deleted from a level once the reference stored in its own level is
+// marked; swapping a whole casRef makes both fields change atomically.
+type casRef[K constraints.Ordered, SCORE constraints.Ordered, V any] struct {
+	forward *casNode[K, SCORE, V]
+	marked  bool
+}
+
+type casNode[K constraints.Ordered, SCORE constraints.Ordered, V any] struct {
+	key   K
+	score SCORE
+	value atomic.Pointer[V]
+	level []atomic.Pointer[casRef[K, SCORE, V]]
+}
+
+// less reports whether the node is ordered before (score, key), in the order
+// of cmp.Compare like the scans, so that NaN scores are ordered first
+func (this *casNode[K, SCORE, V]) less(score SCORE, key K) bool {
+	c := cmp.Compare(this.score, score)
+	return c < 0 || (c == 0 && cmp.Compare(this.key, key) < 0)
+}
+
+func (this *casNode[K, SCORE, V]) removed() bool {
+	return this.level[0].Load().marked
+}
+
+func (this *casNode[K, SCORE, V]) copy() *SortedSetNode[K, SCORE, V] {
+	return &SortedSetNode[K, SCORE, V]{
+		key:   this.key,
+		Value: *this.value.Load(),
+		score: this.score,
+	}
+}
+
+// CASSortedSet is an alternative SortedSet backend whose skip list is updated
+// with compare-and-swap on atomic forward pointers, using the marked-deletion
+// scheme of Herlihy and Shavit's LockFreeSkipList. All methods are safe for
+// concurrent use and none of them takes a global lock.
+//
+// The set is not lock-free: writers of the same key are serialized by one of
+// a fixed pool of striped mutexes, so that a key has at most one node in the
+// list. Writers whose keys map to different mutexes, and all readers, proceed
+// in parallel; readers never take a lock.
+//
+// Guarantees:
+//
+//   - AddOrUpdate, Remove, GetByKey and Has are linearizable per key.
+//   - PeekMin, PopMin, PeekMax and PopMax are linearizable per key, and weakly
+//     consistent with respect to concurrent inserts: the node to pop is
+//     chosen without a lock, so a key inserted before it meanwhile does not
+//     stop it from being popped and returned as the extreme.
+//   - GetRangeByScore is weakly consistent: it returns every node present for
+//     the whole scan and never a node removed before the scan started. An
+//     update that moves a key to a new score removes the old node before
+//     linking the new one, so a concurrent scan may miss that key.
+//   - The nodes have no rank spans. GetCount is an approximate counter under
+//     concurrent writes, and FindRank and GetRangeByRank walk the bottom level
+//     from the first node in O(N); their results are approximate while
+//     writers are active and exact once they are quiescent.
+type CASSortedSet[K constraints.Ordered, SCORE constraints.Ordered, V any] struct {
+	header *casNode[K, SCORE, V]
+	length atomic.Int64
+	dict   sync.Map // key K -> *casNode[K, SCORE, V]
+	seed   maphash.Seed
+	locks  [casKeyLocks]sync.Mutex
+}
+
+func createCASNode[K constraints.Ordered, SCORE constraints.Ordered, V any](level int, score SCORE, key K, value V) *casNode[K, SCORE, V] {
+	node := &casNode[K, SCORE, V]{
+		key:   key,
+		score: score,
+		level: make([]atomic.Pointer[casRef[K, SCORE, V]], level),
+	}
+	node.value.Store(&value)
+	for i := range node.level {
+		node.level[i].Store(&casRef[K, SCORE, V]{})
+	}
+	return node
+}
+
+// Create a new CASSortedSet
+func NewCAS[K constraints.Ordered, SCORE constraints.Ordered, V any]() *CASSortedSet[K, SCORE, V] {
+	var emptyKey K
+	var emptyScore SCORE
+	var emptyValue V
+	return &CASSortedSet[K, SCORE, V]{
+		header: createCASNode(SKIPLIST_MAXLEVEL, emptyScore, emptyKey, emptyValue),
+		seed:   maphash.MakeSeed(),
+	}
+}
+
+func (this *CASSortedSet[K, SCORE, V]) lockKey(key K) *sync.Mutex {
+	mutex := &this.locks[maphash.Comparable(this.seed, key)%casKeyLocks]
+	mutex.Lock()
+	return mutex
+}
+
+func (this *CASSortedSet[K, SCORE, V]) lookup(key K) *casNode[K, SCORE, V] {
+	if v, ok := this.dict.Load(key); ok {
+		return v.(*casNode[K, SCORE, V])
+	}
+	return nil
+}
+
+// find fills update/next with the predecessors and successors of (score, key)
+// on every level, physically unlinking the marked nodes it passes. It reports
+// whether next[0] is the node of (score, key).
+func (this *CASSortedSet[K, SCORE, V]) find(score SCORE, key K, update, next *[SKIPLIST_MAXLEVEL]*casNode[K, SCORE, V]) bool {
+retry:
+	x := this.header
+	for i := SKIPLIST_MAXLEVEL - 1; i >= 0; i-- {
+		current := x.level[i].Load().forward
+		for current != nil {
+			ref := current.level[i].Load()
+			for ref.marked {
+				// unlink the logically deleted node from this level
+				prev := x.level[i].Load()
+				if prev.forward != current || prev.marked ||
+					!x.level[i].CompareAndSwap(prev, &casRef[K, SCORE, V]{forward: ref.forward}) {
+					goto retry
+				}
+				current = ref.forward
+				if current == nil {
+					break
+				}
+				ref = current.level[i].Load()
+			}
+			if current == nil || !current.less(score, key) {
+				break
+			}
+			x = current
+			current = ref.forward
+		}
+		update[i] = x
+		next[i] = current
+	}
+	return next[0] != nil && cmp.Compare(next[0].score, score) == 0 && cmp.Compare(next[0].key, key) == 0
+}
+
+// insertNode links a new node for (score, key), which must not be in the list
+func (this *CASSortedSet[K, SCORE, V]) insertNode(score SCORE, key K, value V) *casNode[K, SCORE, V] {
+	var update, next [SKIPLIST_MAXLEVEL]*casNode[K, SCORE, V]
+
+	level := randomLevel()
+	x := createCASNode(level, score, key, value)
+	for {
+		this.find(score, key, &update, &next)
+		for i := 0; i < level; i++ {
+			x.level[i].Store(&casRef[K, SCORE, V]{forward: next[i]})
+		}
+		// linking the bottom level makes the node a member of the set
+		prev := update[0].level[0].Load()
+		if prev.forward == next[0] && !prev.marked &&
+			update[0].level[0].CompareAndSwap(prev, &casRef[K, SCORE, V]{forward: x}) {
+			break
+		}
+	}
+
+	for i := 1; i < level; i++ {
+		for {
+			prev := update[i].level[i].Load()
+			if prev.forward == next[i] && !prev.marked &&
+				update[i].level[i].CompareAndSwap(prev, &casRef[K, SCORE, V]{forward: x}) {
+				break
+			}
+			this.find(score, key, &update, &next)
+			ref := x.level[i].Load()
+			if ref.marked {
+				return x // already being removed, stop linking upper levels
+			}
+			if ref.forward != next[i] {
+				x.level[i].CompareAndSwap(ref, &casRef[K, SCORE, V]{forward: next[i]})
+			}
+		}
+	}
+	return x
+}
+
+// deleteNode marks x on every level, top-down, then lets find unlink it.
+// It returns false if x had already been deleted by another goroutine.
+func (this *CASSortedSet[K, SCORE, V]) deleteNode(x *casNode[K, SCORE, V]) bool {
+	for i := len(x.level) - 1; i >= 1; i-- {
+		ref := x.level[i].Load()
+		for !ref.marked {
+			x.level[i].CompareAndSwap(ref, &casRef[K, SCORE, V]{forward: ref.forward, marked: true})
+			ref = x.level[i].Load()
+		}
+	}
+	for {
+		ref := x.level[0].Load()
+		if ref.marked {
+			return false
+		}
+		if x.level[0].CompareAndSwap(ref, &casRef[K, SCORE, V]{forward: ref.forward, marked: true}) {
+			var update, next [SKIPLIST_MAXLEVEL]*casNode[K, SCORE, V]
+			this.find(x.score, x.key, &update, &next)
+			return true
+		}
+	}
+}
+
+// first returns the first node not deleted at the moment it is read
+func (this *CASSortedSet[K, SCORE, V]) first() *casNode[K, SCORE, V] {
+	x := this.header.level[0].Load().forward
+	for x != nil && x.removed() {
+		x = x.level[0].Load().forward
+	}
+	return x
+}
+
+// last returns the last node not deleted at the moment it is read. The nodes
+// have no backward pointers, so when the last node is being removed the search
+// restarts for the last node ordered before it.
+func (this *CASSortedSet[K, SCORE, V]) last() *casNode[K, SCORE, V] {
+	var bound *casNode[K, SCORE, V]
+	for {
+		x := this.header
+		for i := SKIPLIST_MAXLEVEL - 1; i >= 0; i-- {
+			for {
+				next := x.level[i].Load().forward
+				if next == nil || (bound != nil && !next.less(bound.score, bound.key)) {
+					break
+				}
+				x = next
+			}
+		}
+		if x == this.header {
+			return nil
+		}
+		if !x.removed() {
+			return x
+		}
+		bound = x
+	}
+}
+
+// removeCurrent removes x if it is still the node of its key, and reports
+// whether it did
+func (this *CASSortedSet[K, SCORE, V]) removeCurrent(x *casNode[K, SCORE, V]) bool {
+	mutex := this.lockKey(x.key)
+	defer mutex.Unlock()
+	// the node may have been removed or replaced while taking the lock
+	if this.lookup(x.key) != x {
+		return false
+	}
+	this.dict.Delete(x.key)
+	this.deleteNode(x)
+	this.length.Add(-1)
+	return true
+}
+
+// Get the number of elements
+//
+// The counter is updated after the list, so it is approximate while writers are active
+func (this *CASSortedSet[K, SCORE, V]) GetCount() int {
+	return int(this.length.Load())
+}
+
+// Add an element into the sorted set with specific key / value / score.
+// if the element is added, this method returns true; otherwise false means updated
+//
+// Time complexity of this method is : O(log(N))
+func (this *CASSortedSet[K, SCORE, V]) AddOrUpdate(key K, score SCORE, value V) bool {
+	mutex := this.lockKey(key)
+	defer mutex.Unlock()
+
+	found := this.lookup(key)
+	if found != nil {
+		// score does not change, only update value
+		if cmp.Compare(found.score, score) == 0 {
+			found.value.Store(&value)
+			return false
+		}
+		this.deleteNode(found)
+	}
+	this.dict.Store(key, this.insertNode(score, key, value))
+	if found == nil {
+		this.length.Add(1)
+	}
+	return found == nil
+}
+
+// Delete element specified by key, returning a copy of the removed node
+//
+// Time complexity of this method is : O(log(N))
+func (this *CASSortedSet[K, SCORE, V]) Remove(key K) *SortedSetNode[K, SCORE, V] {
+	mutex := this.lockKey(key)
+	defer mutex.Unlock()
+
+	found := this.lookup(key)
+	if found == nil {
+		return nil
+	}
+	this.dict.Delete(key)
+	this.deleteNode(found)
+	this.length.Add(-1)
+	return found.copy()
+}
+
+// Has reports whether key is a member of the set.
+func (this *CASSortedSet[K, SCORE, V]) Has(key K) bool {
+	_, ok := this.dict.Load(key)
+	return ok
+}
+
+// Get a copy of the node by key
+//
+// If node is not found, nil is returned
+// Time complexity : O(1)
+func (this *CASSortedSet[K, SCORE, V]) GetByKey(key K) *SortedSetNode[K, SCORE, V] {
+	if found := this.lookup(key); found != nil {
+		return found.copy()
+	}
+	return nil
+}
+
+// get a copy of the element with minimum score, nil if the set is empty
+func (this *CASSortedSet[K, SCORE, V]) PeekMin() *SortedSetNode[K, SCORE, V] {
+	if x := this.first(); x != nil {
+		return x.copy()
+	}
+	return nil
+}
+
+// get and remove the element with minimal score, nil if the set is empty
+func (this *CASSortedSet[K, SCORE, V]) PopMin() *SortedSetNode[K, SCORE, V] {
+	for {
+		x := this.first()
+		if x == nil {
+			return nil
+		}
+		if this.removeCurrent(x) {
+			return x.copy()
+		}
+	}
+}
+
+// get a copy of the element with maximum score, nil if the set is empty
+//
+// Time complexity of this method is : O(log(N))
+func (this *CASSortedSet[K, SCORE, V]) PeekMax() *SortedSetNode[K, SCORE, V] {
+	if x := this.last(); x != nil {
+		return x.copy()
+	}
+	return nil
+}
+
+// get and remove the element with maximum score, nil if the set is empty
+//
+// Time complexity of this method is : O(log(N))
+func (this *CASSortedSet[K, SCORE, V]) PopMax() *SortedSetNode[K, SCORE, V] {
+	for {
+		x := this.last()
+		if x == nil {
+			return nil
+		}
+		if this.removeCurrent(x) {
+			return x.copy()
+		}
+	}
+}
+
+// Get copies of the nodes whose score within the specific range
+//
+// If options is nil, it searchs in interval [start, end] without any limit by default.
+// If start is greater than end, the nodes are returned in reserved order.
+//
+// Time complexity of this method is : O(log(N)) to locate start, then O(M)
+// for M nodes in the range (reverse scans always visit the whole range)
+func (this *CASSortedSet[K, SCORE, V]) GetRangeByScore(start SCORE, end SCORE, options *GetRangeByScoreOptions) []*SortedSetNode[K, SCORE, V] {
+	return this.GetRangeByScoreBound(ScoreInclusive(start), ScoreInclusive(end), options)
+}
+
+// Get copies of the nodes whose score within the range between the bounds start and end
+//
+// See SortedSet.GetRangeByScoreBound for the meaning of the arguments
+func (this *CASSortedSet[K, SCORE, V]) GetRangeByScoreBound(start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions) []*SortedSetNode[K, SCORE, V] {
+	var limit int = int((^uint(0)) >> 1)
+	if options != nil && options.Limit > 0 {
+		limit = options.Limit
+	}
+
+	compare := cmp.Compare[SCORE]
+	start, end = applyExcludes(start, end, options)
+	reverse := start.after(compare, end)
+	if reverse {
+		start, end = end, start
+	}
+	if options != nil && options.Reverse {
+		reverse = true
+	}
+
+	// search the last node before the range without unlinking anything
+	x := this.header
+	for i := SKIPLIST_MAXLEVEL - 1; i >= 0; i-- {
+		for {
+			next := x.level[i].Load().forward
+			if next == nil || start.gteMin(compare, next.score) {
+				break
+			}
+			x = next
+		}
+	}
+
+	var nodes []*SortedSetNode[K, SCORE, V]
+	for x = x.level[0].Load().forward; x != nil; x = x.level[0].Load().forward {
+		if !end.lteMax(compare, x.score) {
+			break
+		}
+		if x.removed() {
+			continue
+		}
+		nodes = append(nodes, x.copy())
+		if !reverse && len(nodes) == limit {
+			break
+		}
+	}
+
+	if reverse {
+		for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+			nodes[i], nodes[j] = nodes[j], nodes[i]
+		}
+		if len(nodes) > limit {
+			nodes = nodes[:limit]
+		}
+	}
+	return nodes
+}
+
+// Find the rank of the node specified by key
+// Note that the rank is 1-based integer. Rank 1 means the first node
+//
+// If the node is not found, 0 is returned. Otherwise rank(> 0) is returned
+//
+// The nodes have no rank spans, so the rank is counted by walking the bottom
+// level from the first node. The result is approximate while writers are active.
+//
+// Time complexity of this method is : O(N)
+func (this *CASSortedSet[K, SCORE, V]) FindRank(key K) int {
+	found := this.lookup(key)
+	if found == nil {
+		return 0
+	}
+	rank := 0
+	for x := this.header.level[0].Load().forward; x != nil; x = x.level[0].Load().forward {
+		if x.removed() {
+			continue
+		}
+		rank++
+		if x == found {
+			return rank
+		}
+	}
+	return 0
+}
+
+// Get copies of the nodes within specific rank range [start, end]
+// Note that the rank is 1-based integer. Rank 1 means the first node; Rank -1 means the last node;
+//
+// If start is greater than end, the returned array is in reserved order
+// If remove is true, the returned nodes are removed. A node removed or moved
+// by another goroutine during the call is not returned.
+//
+// The nodes have no rank spans, so the range is found by walking the bottom
+// level from the first node. Negative ranks are relative to the number of
+// nodes seen by that walk. The result is approximate while writers are active.
+//
+// Time complexity of this method is : O(N)
+func (this *CASSortedSet[K, SCORE, V]) GetRangeByRank(start int, end int, remove bool) []*SortedSetNode[K, SCORE, V] {
+	var all []*casNode[K, SCORE, V]
+	if start < 0 || end < 0 {
+		for x := this.header.level[0].Load().forward; x != nil; x = x.level[0].Load().forward {
+			if !x.removed() {
+				all = append(all, x)
+			}
+		}
+	}
+	length := len(all)
+	if start < 0 {
+		start = length + start + 1
+	}
+	if end < 0 {
+		end = length + end + 1
+	}
+	start, end = max(start, 1), max(end, 1)
+	reverse := start > end
+	if reverse {
+		start, end = end, start
+	}
+
+	var found []*casNode[K, SCORE, V]
+	if all != nil {
+		if start <= length {
+			found = all[start-1 : min(end, length)]
+		}
+	} else {
+		rank := 0
+		for x := this.header.level[0].Load().forward; x != nil && rank < end; x = x.level[0].Load().forward {
+			if x.removed() {
+				continue
+			}
+			rank++
+			if rank >= start {
+				found = append(found, x)
+			}
+		}
+	}
+
+	nodes := make([]*SortedSetNode[K, SCORE, V], 0, len(found))
+	for _, x := range found {
+		if remove && !this.removeCurrent(x) {
+			continue
+		}
+		nodes = append(nodes, x.copy())
+	}
+
+	if reverse {
+		for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+			nodes[i], nodes[j] = nodes[j], nodes[i]
+		}
+	}
+	return nodes
+}
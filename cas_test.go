@@ -0,0 +1,143 @@
+package sortedset
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestCASSortedSet(t *testing.T) {
+	sortedset := NewCAS[string, int64, string]()
+
+	sortedset.AddOrUpdate("a", 89, "Kelly")
+	sortedset.AddOrUpdate("b", 100, "Staley")
+	sortedset.AddOrUpdate("c", 100, "Jordon")
+	sortedset.AddOrUpdate("d", -321, "Park")
+	sortedset.AddOrUpdate("e", 101, "Albert")
+	sortedset.AddOrUpdate("f", 99, "Lyman")
+	sortedset.AddOrUpdate("g", 99, "Singleton")
+	sortedset.AddOrUpdate("h", 70, "Audrey")
+
+	if sortedset.AddOrUpdate("e", 99, "ntrnrt") {
+		t.Error("AddOrUpdate() reported an update as an insert")
+	}
+	sortedset.Remove("b")
+
+	checkOrder(t, sortedset.GetRangeByScore(-500, 500, nil), []string{"d", "h", "a", "e", "f", "g", "c"})
+	checkOrder(t, sortedset.GetRangeByScore(500, -500, nil), []string{"c", "g", "f", "e", "a", "h", "d"})
+	checkOrder(t, sortedset.GetRangeByScore(99, 100, &GetRangeByScoreOptions{ExcludeStart: true}), []string{"c"})
+	checkOrder(t, sortedset.GetRangeByScore(100, 99, &GetRangeByScoreOptions{ExcludeEnd: true}), []string{"c"})
+	checkOrder(t, sortedset.GetRangeByScore(100, 50, &GetRangeByScoreOptions{Limit: 2}), []string{"c", "g"})
+	checkOrder(t, sortedset.GetRangeByRank(1, -1, false), []string{"d", "h", "a", "e", "f", "g", "c"})
+	checkOrder(t, sortedset.GetRangeByRank(-2, -3, false), []string{"g", "f"})
+	checkOrder(t, sortedset.GetRangeByRank(2, 3, false), []string{"h", "a"})
+
+	if rank := sortedset.FindRank("f"); rank != 5 {
+		t.Errorf("FindRank() returned %d, expected 5", rank)
+	}
+	if node := sortedset.GetByKey("e"); node == nil || node.Score() != 99 || node.Value != "ntrnrt" {
+		t.Error("GetByKey() does not return the updated node")
+	}
+	if node := sortedset.PopMin(); node == nil || node.Key() != "d" {
+		t.Error("PopMin() does not return expected value `d`")
+	}
+	if node := sortedset.PeekMin(); node == nil || node.Key() != "h" {
+		t.Error("PeekMin() does not return expected value `h`")
+	}
+	if sortedset.GetCount() != 6 || sortedset.Has("d") {
+		t.Errorf("GetCount() returned %d, expected 6", sortedset.GetCount())
+	}
+
+	if node := sortedset.PeekMax(); node == nil || node.Key() != "c" {
+		t.Error("PeekMax() does not return expected value `c`")
+	}
+	if node := sortedset.PopMax(); node == nil || node.Key() != "c" {
+		t.Error("PopMax() does not return expected value `c`")
+	}
+	if node := sortedset.PeekMax(); node == nil || node.Key() != "g" {
+		t.Error("PeekMax() does not return expected value `g`")
+	}
+
+	// h a e f g
+	checkOrder(t, sortedset.GetRangeByRank(-1, -2, true), []string{"g", "f"})
+	checkOrder(t, sortedset.GetRangeByRank(1, -1, false), []string{"h", "a", "e"})
+	if sortedset.GetCount() != 3 || sortedset.Has("g") {
+		t.Errorf("GetCount() returned %d, expected 3", sortedset.GetCount())
+	}
+	sortedset.GetRangeByRank(1, -1, true)
+	if sortedset.PeekMax() != nil || sortedset.PopMax() != nil || sortedset.GetCount() != 0 {
+		t.Error("the set is not empty after removing every rank")
+	}
+}
+
+func TestCASSortedSetConcurrentWriters(t *testing.T) {
+	sortedset := NewCAS[int, int, int]()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := (i*7 + w) % 300
+				sortedset.AddOrUpdate(key, i%97, w)
+				switch i % 13 {
+				case 0:
+					sortedset.Remove(key)
+				case 6:
+					sortedset.PopMin()
+				case 9:
+					sortedset.PopMax()
+				}
+				sortedset.GetRangeByScore(10, 50, &GetRangeByScoreOptions{Limit: 5})
+			}
+		}()
+	}
+	wg.Wait()
+
+	// once quiescent, the list, the index and the counter agree
+	nodes := sortedset.GetRangeByRank(1, -1, false)
+	if len(nodes) != sortedset.GetCount() {
+		t.Fatalf("list holds %d nodes but GetCount() is %d", len(nodes), sortedset.GetCount())
+	}
+	for i, node := range nodes {
+		if !sortedset.Has(node.Key()) {
+			t.Fatalf("node %d is not indexed", node.Key())
+		}
+		if i > 0 && (nodes[i-1].Score() > node.Score() ||
+			(nodes[i-1].Score() == node.Score() && nodes[i-1].Key() >= node.Key())) {
+			t.Fatalf("nodes %d and %d are out of order", nodes[i-1].Key(), node.Key())
+		}
+	}
+}
+
+func TestCASSortedSetNaN(t *testing.T) {
+	sortedset := NewCAS[string, float64, string]()
+	sortedset.AddOrUpdate("a", 1, "")
+	sortedset.AddOrUpdate("nan", math.NaN(), "")
+	sortedset.AddOrUpdate("b", 2, "")
+	sortedset.AddOrUpdate("c", 0, "")
+	if sortedset.AddOrUpdate("nan", math.NaN(), "again") {
+		t.Error("AddOrUpdate() reported an update of a NaN score as an insert")
+	}
+
+	// NaN is ordered first, like cmp.Compare does in the scans
+	var keys []string
+	for _, node := range sortedset.GetRangeByScore(math.Inf(-1), math.Inf(1), nil) {
+		keys = append(keys, node.Key())
+	}
+	if fmt.Sprint(keys) != "[c a b]" {
+		t.Errorf("GetRangeByScore() returned %v", keys)
+	}
+	keys = keys[:0]
+	for _, node := range sortedset.GetRangeByRank(1, -1, false) {
+		keys = append(keys, node.Key())
+	}
+	if fmt.Sprint(keys) != "[nan c a b]" {
+		t.Errorf("GetRangeByRank() returned %v", keys)
+	}
+	if sortedset.GetCount() != 4 {
+		t.Errorf("GetCount() returned %d", sortedset.GetCount())
+	}
+}
@@ -0,0 +1,372 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// cloneChunk is the number of nodes a copier copies each time it locks the set
+const cloneChunk = 256
+
+// ReadOnly is an immutable copy of a SortedSet, returned by Clone.
+//
+// Unlike the set it was copied from, every method of a ReadOnly is safe to call
+// from any goroutine, concurrently with each other and with the owner still
+// writing to the original set. The methods block until the copy is complete.
+// Nodes returned by a ReadOnly belong to the copy; callers must not modify
+// their Value.
+type ReadOnly[K comparable, SCORE any, V any] struct {
+	set   *SortedSet[K, SCORE, V]
+	ready chan struct{} // closed once the copy is complete
+	// time of the clone in Unix nanoseconds if elements had a deadline, 0 otherwise
+	now int64
+	// deadlines of the copied elements, kept for Journaled.Rewrite only
+	deadlines []keyDeadline[K]
+}
+
+// keyDeadline is the deadline of a key in Unix nanoseconds
+type keyDeadline[K comparable] struct {
+	key      K
+	deadline int64
+}
+
+// cloneState tracks the copiers of a set, see Clone
+type cloneState[K comparable, SCORE any, V any] struct {
+	// held by a copier while it reads the nodes, and by the owner while it
+	// changes them, as long as copiers are running
+	mutex   sync.Mutex
+	copying int32 // number of copiers, read atomically, only raised by the owner
+	copiers []*cloneCopier[K, SCORE, V]
+}
+
+// cloneCopier copies the set as it was when Clone was called, while the
+// owner keeps writing to it
+//
+// The nodes are copied in order, cloneChunk at a time. Before the owner
+// changes a key, it calls touch: the copier skips the touched keys in the
+// set, and copies instead the state they had at the time of the clone, which
+// touch keeps in pending unless the key was copied already.
+type cloneCopier[K comparable, SCORE any, V any] struct {
+	set    *SortedSet[K, SCORE, V]
+	header *SortedSetNode[K, SCORE, V] // header of the set at the time of the clone
+	clone  *ReadOnly[K, SCORE, V]
+	// position of the last copied node, if started
+	started bool
+	score   SCORE
+	key     K
+	touched map[K]struct{}
+	pending *SortedSet[K, SCORE, V]
+	// deadlines of the pending keys, if keepDeadlines
+	deadlines     map[K]int64
+	keepDeadlines bool
+	builder       *sortedSetBuilder[K, SCORE, V]
+}
+
+// Clone returns a read-only copy of the set as it is when Clone is called.
+// It must be called from the goroutine owning the set.
+//
+// Clone returns at once: the copy is built by a background goroutine while the
+// owner keeps writing to the set, like a copy-on-write snapshot. Until the copy
+// is complete, a change of the set waits for the copier to finish its current
+// chunk of nodes, and the first change of each key saves the state the key had
+// for the copy. Expired elements are left out, the elements of the copy do not
+// expire, and the values are shallow copies.
+//
+// Time complexity of this method is : O(1), then O(N) in the background
+func (this *SortedSet[K, SCORE, V]) Clone() *ReadOnly[K, SCORE, V] {
+	return this.newCloneCopier(false).clone
+}
+
+// newCloneCopier registers a copier of the set and starts it
+func (this *SortedSet[K, SCORE, V]) newCloneCopier(keepDeadlines bool) *cloneCopier[K, SCORE, V] {
+	copier := this.registerCloneCopier(keepDeadlines)
+	go copier.run()
+	return copier
+}
+
+// registerCloneCopier registers a copier of the set, which run must complete
+func (this *SortedSet[K, SCORE, V]) registerCloneCopier(keepDeadlines bool) *cloneCopier[K, SCORE, V] {
+	clone := &ReadOnly[K, SCORE, V]{set: this.newLike(), ready: make(chan struct{})}
+	if this.expiry != nil && this.expiry.length > 0 {
+		clone.now = this.now()
+	}
+	copier := &cloneCopier[K, SCORE, V]{
+		set:           this,
+		header:        this.header,
+		clone:         clone,
+		touched:       make(map[K]struct{}),
+		pending:       NewFunc[K, SCORE, V](this.scoreCmp, this.keyCmp),
+		deadlines:     make(map[K]int64),
+		keepDeadlines: keepDeadlines,
+		builder:       newSortedSetBuilder(clone.set),
+	}
+	this.clones.mutex.Lock()
+	this.clones.copiers = append(this.clones.copiers, copier)
+	atomic.AddInt32(&this.clones.copying, 1)
+	this.clones.mutex.Unlock()
+	return copier
+}
+
+// cloning is called by the owner before it changes the node of key, or links
+// a node for key. If copiers are running, it locks them out, lets them save
+// the state of key and returns true: the caller must then call unlockClones
+// once the change is done.
+func (this *SortedSet[K, SCORE, V]) cloning(key K) bool {
+	if atomic.LoadInt32(&this.clones.copying) == 0 {
+		return false
+	}
+	this.clones.mutex.Lock()
+	for _, copier := range this.clones.copiers {
+		copier.touch(key)
+	}
+	return true
+}
+
+func (this *SortedSet[K, SCORE, V]) unlockClones() {
+	this.clones.mutex.Unlock()
+}
+
+// detachClones is called by reset: the copiers keep reading the previous
+// nodes, which the owner no longer changes
+func (this *SortedSet[K, SCORE, V]) detachClones() {
+	if atomic.LoadInt32(&this.clones.copying) == 0 {
+		return
+	}
+	this.clones.mutex.Lock()
+	this.clones.copiers = nil
+	atomic.StoreInt32(&this.clones.copying, 0)
+	this.clones.mutex.Unlock()
+}
+
+// touch saves the state of key at the time of the clone, before its first change
+func (this *cloneCopier[K, SCORE, V]) touch(key K) {
+	if _, ok := this.touched[key]; ok {
+		return
+	}
+	this.touched[key] = struct{}{}
+	x := this.set.lookup(key)
+	if x == nil || !x.aliveAt(this.clone.now) {
+		return
+	}
+	if this.started && this.set.compare(x, this.score, this.key) <= 0 {
+		return // copied already
+	}
+	this.pending.AddOrUpdate(key, x.score, x.Value)
+	if this.keepDeadlines && x.deadline != 0 {
+		this.deadlines[key] = x.deadline
+	}
+}
+
+func (this *cloneCopier[K, SCORE, V]) run() {
+	for !this.step() {
+	}
+	this.builder.finish()
+	close(this.clone.ready)
+}
+
+// step copies at most cloneChunk nodes and reports whether the copy is complete
+func (this *cloneCopier[K, SCORE, V]) step() bool {
+	clones := &this.set.clones
+	clones.mutex.Lock()
+	defer clones.mutex.Unlock()
+
+	x := this.seek()
+	for i := 0; i < cloneChunk; i++ {
+		for x != nil && !this.copies(x) {
+			x = x.level[0].forward
+		}
+		p := this.pending.header.level[0].forward
+		if x == nil && p == nil {
+			this.detach()
+			return true
+		}
+		if p != nil && (x == nil || this.set.compare(x, p.score, p.key) > 0) {
+			this.pending.Remove(p.key)
+			this.copy(p.score, p.key, p.Value, this.deadlines[p.key])
+		} else {
+			this.copy(x.score, x.key, x.Value, x.deadline)
+			x = x.level[0].forward
+		}
+	}
+	return false
+}
+
+// seek returns the first node after the last copied one
+func (this *cloneCopier[K, SCORE, V]) seek() *SortedSetNode[K, SCORE, V] {
+	x := this.header
+	if this.started {
+		for i := SKIPLIST_MAXLEVEL - 1; i >= 0; i-- {
+			for x.level[i].forward != nil &&
+				this.set.compare(x.level[i].forward, this.score, this.key) <= 0 {
+				x = x.level[i].forward
+			}
+		}
+	}
+	return x.level[0].forward
+}
+
+// copies reports whether x is copied as it is
+func (this *cloneCopier[K, SCORE, V]) copies(x *SortedSetNode[K, SCORE, V]) bool {
+	_, touched := this.touched[x.key]
+	return !touched && x.aliveAt(this.clone.now)
+}
+
+func (this *cloneCopier[K, SCORE, V]) copy(score SCORE, key K, value V, deadline int64) {
+	this.builder.append(score, key, value)
+	this.started, this.score, this.key = true, score, key
+	if this.keepDeadlines && deadline != 0 {
+		this.clone.deadlines = append(this.clone.deadlines, keyDeadline[K]{key: key, deadline: deadline})
+	}
+}
+
+// detach unregisters the copier, the mutex of the set must be held
+func (this *cloneCopier[K, SCORE, V]) detach() {
+	clones := &this.set.clones
+	for i, copier := range clones.copiers {
+		if copier == this {
+			clones.copiers = append(clones.copiers[:i], clones.copiers[i+1:]...)
+			atomic.AddInt32(&clones.copying, -1)
+			return
+		}
+	}
+}
+
+// wait returns the copy once it is complete
+func (this *ReadOnly[K, SCORE, V]) wait() *SortedSet[K, SCORE, V] {
+	<-this.ready
+	return this.set
+}
+
+// Get the number of elements
+func (this *ReadOnly[K, SCORE, V]) GetCount() int {
+	return this.wait().GetCount()
+}
+
+// Has reports whether key is a member of the copy
+func (this *ReadOnly[K, SCORE, V]) Has(key K) bool {
+	return this.wait().Has(key)
+}
+
+// get the element with minimum score, nil if the copy is empty
+func (this *ReadOnly[K, SCORE, V]) PeekMin() *SortedSetNode[K, SCORE, V] {
+	return this.wait().PeekMin()
+}
+
+// get the element with maximum score, nil if the copy is empty
+func (this *ReadOnly[K, SCORE, V]) PeekMax() *SortedSetNode[K, SCORE, V] {
+	return this.wait().PeekMax()
+}
+
+// Get node by key
+//
+// If node is not found, nil is returned
+func (this *ReadOnly[K, SCORE, V]) GetByKey(key K) *SortedSetNode[K, SCORE, V] {
+	return this.wait().GetByKey(key)
+}
+
+// Get the nodes whose score within the specific range
+//
+// See SortedSet.GetRangeByScore for the meaning of the arguments
+func (this *ReadOnly[K, SCORE, V]) GetRangeByScore(start SCORE, end SCORE, options *GetRangeByScoreOptions) []*SortedSetNode[K, SCORE, V] {
+	return this.wait().GetRangeByScore(start, end, options)
+}
+
+// Get the nodes whose score within the range between the bounds start and end
+//
+// See SortedSet.GetRangeByScoreBound for the meaning of the arguments
+func (this *ReadOnly[K, SCORE, V]) GetRangeByScoreBound(start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions) []*SortedSetNode[K, SCORE, V] {
+	return this.wait().GetRangeByScoreBound(start, end, options)
+}
+
+// Get nodes within specific rank range [start, end]
+//
+// See SortedSet.GetRangeByRank for the meaning of the arguments
+func (this *ReadOnly[K, SCORE, V]) GetRangeByRank(start int, end int) []*SortedSetNode[K, SCORE, V] {
+	return this.wait().GetRangeByRank(start, end, false)
+}
+
+// Get node by rank, nil if node is not found at specific rank
+func (this *ReadOnly[K, SCORE, V]) GetByRank(rank int) *SortedSetNode[K, SCORE, V] {
+	return this.wait().GetByRank(rank, false)
+}
+
+// Find the rank of the node specified by key, 0 if the node is not found
+func (this *ReadOnly[K, SCORE, V]) FindRank(key K) int {
+	return this.wait().FindRank(key)
+}
+
+// IterFuncRangeByRank apply fn to node within specific rank range [start, end]
+// or until fn return false
+func (this *ReadOnly[K, SCORE, V]) IterFuncRangeByRank(start int, end int, fn func(key K, value V) bool) {
+	this.wait().IterFuncRangeByRank(start, end, fn)
+}
+
+// All returns an iterator over the key/value pairs of the copy, from the
+// minimum score to the maximum score
+func (this *ReadOnly[K, SCORE, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		this.wait().All()(yield)
+	}
+}
+
+// Backward returns an iterator over the key/value pairs of the copy, from the
+// maximum score to the minimum score
+func (this *ReadOnly[K, SCORE, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		this.wait().Backward()(yield)
+	}
+}
+
+// RangeByScore returns an iterator over the nodes whose score within the
+// specific range
+//
+// See SortedSet.RangeByScore for the meaning of the arguments
+func (this *ReadOnly[K, SCORE, V]) RangeByScore(start SCORE, end SCORE, options *GetRangeByScoreOptions) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		this.wait().RangeByScore(start, end, options)(yield)
+	}
+}
+
+// RangeByScoreBound returns an iterator over the nodes whose score within the
+// range between the bounds start and end
+//
+// See SortedSet.RangeByScoreBound for the meaning of the arguments
+func (this *ReadOnly[K, SCORE, V]) RangeByScoreBound(start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		this.wait().RangeByScoreBound(start, end, options)(yield)
+	}
+}
+
+// RangeByRank returns an iterator over the nodes within specific rank range [start, end]
+//
+// See SortedSet.RangeByRank for the meaning of the arguments
+func (this *ReadOnly[K, SCORE, V]) RangeByRank(start int, end int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		this.wait().RangeByRank(start, end)(yield)
+	}
+}
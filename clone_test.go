@@ -0,0 +1,177 @@
+package sortedset
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClone(t *testing.T) {
+	sortedset := New[string, int64, string]()
+	sortedset.AddOrUpdate("a", 89, "Kelly")
+	sortedset.AddOrUpdate("b", 100, "Staley")
+	sortedset.AddOrUpdate("c", 100, "Jordon")
+	sortedset.AddOrUpdate("d", -321, "Park")
+	sortedset.AddOrUpdate("h", 70, "Audrey")
+
+	clone := sortedset.Clone()
+
+	// writes after the clone are not visible in it
+	sortedset.AddOrUpdate("a", 1000, "Kelly")
+	sortedset.AddOrUpdate("z", 0, "Zed")
+	sortedset.Remove("c")
+
+	checkOrder(t, clone.GetRangeByRank(1, -1), []string{"d", "h", "a", "b", "c"})
+	checkOrder(t, clone.GetRangeByScore(100, 70, &GetRangeByScoreOptions{ExcludeStart: true}), []string{"a", "h"})
+	checkOrder(t, sortedset.GetRangeByRank(1, -1, false), []string{"d", "z", "h", "b", "a"})
+
+	if rank := clone.FindRank("c"); rank != 5 {
+		t.Errorf("FindRank() returned %d, expected 5", rank)
+	}
+	if node := clone.GetByRank(-2); node == nil || node.Key() != "b" {
+		t.Error("GetByRank() does not return expected value `b`")
+	}
+	if clone.GetCount() != 5 || clone.Has("z") || !clone.Has("c") {
+		t.Error("clone membership does not match the set when it was taken")
+	}
+	if clone.PeekMin().Key() != "d" || clone.PeekMax().Key() != "c" {
+		t.Error("PeekMin()/PeekMax() do not return the clone extremes")
+	}
+	checkIterByRankRange(t, clone.wait(), -1, 1, []string{"c", "b", "a", "h", "d"})
+	checkSeq(t, clone.All(), []string{"d", "h", "a", "b", "c"})
+	checkSeq(t, clone.Backward(), []string{"c", "b", "a", "h", "d"})
+	checkSeq(t, clone.RangeByScore(100, 70, &GetRangeByScoreOptions{Limit: 3}), []string{"c", "b", "a"})
+	checkSeq(t, clone.RangeByScoreBound(ScoreExclusive[int64](70), ScoreInclusive[int64](100), nil), []string{"a", "b", "c"})
+	checkSeq(t, clone.RangeByRank(2, 3), []string{"h", "a"})
+
+	// the rebuilt spans must support further inserts into the copy
+	clone.wait().AddOrUpdate("e", 95, "Albert")
+	checkOrder(t, clone.wait().GetRangeByRank(3, 5, false), []string{"a", "e", "b"})
+}
+
+// run with -race: readers use a copy while the owner keeps writing.
+func TestCloneConcurrentReaders(t *testing.T) {
+	sortedset := New[int64, int64, int64]()
+	for i := int64(0); i < 1000; i++ {
+		sortedset.AddOrUpdate(i, i%100, i)
+	}
+	clone := sortedset.Clone()
+
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if nodes := clone.GetRangeByRank(1, 10); len(nodes) != 10 {
+					t.Errorf("GetRangeByRank() returned %d nodes", len(nodes))
+					return
+				}
+				clone.GetRangeByScore(10, 20, nil)
+				clone.FindRank(int64(i))
+			}
+		}()
+	}
+	for i := int64(0); i < 2000; i++ {
+		sortedset.AddOrUpdate(i, -i, i)
+		sortedset.PopMax()
+	}
+	wg.Wait()
+
+	if clone.GetCount() != 1000 {
+		t.Errorf("clone count changed to %d", clone.GetCount())
+	}
+}
+
+func newCloneTestSet(count int) *SortedSet[string, int64, string] {
+	sortedset := New[string, int64, string]()
+	for i := 0; i < count; i++ {
+		sortedset.AddOrUpdate(fmt.Sprintf("k%04d", i), int64(i), "v")
+	}
+	return sortedset
+}
+
+// the copier is stepped by the test, so that the writes land before and
+// after the copied nodes
+func TestCloneCopyOnWrite(t *testing.T) {
+	sortedset := newCloneTestSet(2000)
+	expected, _ := json.Marshal(sortedset)
+
+	copier := sortedset.registerCloneCopier(false)
+	if copier.step() {
+		t.Fatal("the copy completed in one step")
+	}
+	sortedset.Remove("k0010")                   // copied
+	sortedset.Remove("k1000")                   // not copied yet
+	sortedset.AddOrUpdate("k1500", -5, "moved") // moved before the copied nodes
+	sortedset.AddOrUpdate("k0005", 5000, "moved")
+	sortedset.AddOrUpdate("k1200", 1200, "changed")
+	sortedset.AddOrUpdate("new", 700, "new")
+	IncrementScore(sortedset, "k1300", 1)
+	sortedset.PopMin()
+	sortedset.GetRangeByRank(1500, 1510, true)
+	copier.step()
+	sortedset.AddOrUpdate("k1000", 1000, "added again")
+	sortedset.AddWithTTL("k1800", 1800, "", time.Hour)
+	copier.run()
+
+	actual, _ := json.Marshal(copier.clone.wait())
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("the clone changed with the set:\n%s\n%s", actual, expected)
+	}
+	if err := copier.clone.wait().Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if sortedset.clones.copying != 0 || len(sortedset.clones.copiers) != 0 {
+		t.Error("the copier is still registered")
+	}
+}
+
+func TestCloneReset(t *testing.T) {
+	sortedset := newCloneTestSet(1000)
+	expected, _ := json.Marshal(sortedset)
+
+	copier := sortedset.registerCloneCopier(false)
+	copier.step()
+	sortedset.Remove("k0999") // saved for the copier before the reset
+	if err := sortedset.UnmarshalJSON([]byte(`[{"key":"x","score":1,"value":"y"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	// the set is no longer tracked after a reset
+	sortedset.AddOrUpdate("k0500", -1, "")
+	copier.run()
+
+	actual, _ := json.Marshal(copier.clone.wait())
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("the clone changed with the set:\n%s\n%s", actual, expected)
+	}
+}
+
+// run with -race: the owner keeps writing while the copy is built
+func TestCloneConcurrentWrites(t *testing.T) {
+	sortedset := newCloneTestSet(20000)
+	expected, _ := json.Marshal(sortedset)
+
+	clone := sortedset.Clone()
+	for i := 0; i < 20000; i += 3 {
+		key := fmt.Sprintf("k%04d", i)
+		switch i % 4 {
+		case 0:
+			sortedset.Remove(key)
+		case 1:
+			sortedset.AddOrUpdate(key, int64(-i), "moved")
+		case 2:
+			sortedset.AddOrUpdate(key, int64(i), "changed")
+		default:
+			sortedset.AddOrUpdate("new"+key, int64(i), "new")
+		}
+	}
+
+	actual, _ := json.Marshal(clone.wait())
+	if !bytes.Equal(actual, expected) {
+		t.Error("the clone changed with the set")
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Command sortedsetd serves sorted sets over the Redis protocol (RESP2 and
+// RESP3), so that they can be inspected and modified with redis-cli.
+//
+// The keyspace holds SortedSet[string, float64, []byte] values and supports
+// ZADD, ZREM, ZSCORE, ZRANK, ZRANGE, ZCOUNT, ZPOPMIN, ZPOPMAX and ZCARD.
+//
+// Usage:
+//
+//	sortedsetd -addr 127.0.0.1:6379
+//	sortedsetd -unix /tmp/sortedsetd.sock
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6379", "TCP address to listen on")
+	unix := flag.String("unix", "", "Unix socket path to listen on instead of -addr")
+	flag.Parse()
+
+	network, address := "tcp", *addr
+	if *unix != "" {
+		network, address = "unix", *unix
+		os.Remove(address)
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("sortedsetd listening on %s %s", network, listener.Addr())
+
+	if err := NewServer().Serve(listener); err != nil {
+		log.Fatal(err)
+	}
+}
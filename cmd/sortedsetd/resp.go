@@ -0,0 +1,172 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/wangjia184/sortedset/command"
+)
+
+const maxBulkLength = 512 << 20
+
+var errProtocol = errors.New("protocol error")
+
+// readCommand reads a command sent as an array of bulk strings, or inline
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count > 1024*1024 {
+		return nil, errProtocol
+	}
+	args := make([]string, 0, max(count, 0))
+	for i := 0; i < count; i++ {
+		line, err = readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errProtocol
+		}
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 || length > maxBulkLength {
+			return nil, errProtocol
+		}
+		data := make([]byte, length+2)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args = append(args, string(data[:length]))
+	}
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// formatScore formats a score like Redis does
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	case score == math.Trunc(score) && math.Abs(score) < 1e17:
+		return strconv.FormatInt(int64(score), 10)
+	}
+	return strconv.FormatFloat(score, 'g', 17, 64)
+}
+
+// respWriter encodes replies with the protocol version of a connection
+type respWriter struct {
+	writer *bufio.Writer
+	proto  int
+}
+
+func (this *respWriter) line(prefix byte, s string) {
+	this.writer.WriteByte(prefix)
+	this.writer.WriteString(s)
+	this.writer.WriteString("\r\n")
+}
+
+func (this *respWriter) bulk(s string) {
+	this.line('$', strconv.Itoa(len(s)))
+	this.writer.WriteString(s)
+	this.writer.WriteString("\r\n")
+}
+
+func (this *respWriter) double(score float64) {
+	if this.proto < 3 {
+		this.bulk(formatScore(score))
+		return
+	}
+	this.line(',', formatScore(score))
+}
+
+func (this *respWriter) write(r command.Reply) {
+	switch r := r.(type) {
+	case command.Status:
+		this.line('+', string(r))
+	case command.Error:
+		this.line('-', string(r))
+	case command.Nil:
+		if this.proto < 3 {
+			this.writer.WriteString("$-1\r\n")
+		} else {
+			this.writer.WriteString("_\r\n")
+		}
+	case command.Bulk:
+		this.bulk(string(r))
+	case command.Double:
+		this.double(float64(r))
+	case command.Integer:
+		this.line(':', strconv.FormatInt(int64(r), 10))
+	case command.Array:
+		this.line('*', strconv.Itoa(len(r)))
+		for _, item := range r {
+			this.write(item)
+		}
+	case command.Map:
+		if this.proto < 3 {
+			this.line('*', strconv.Itoa(len(r)))
+		} else {
+			this.line('%', strconv.Itoa(len(r)/2))
+		}
+		for _, item := range r {
+			this.write(item)
+		}
+	case command.ScoredMembers:
+		// RESP2 flattens the pairs, RESP3 nests them
+		if this.proto < 3 {
+			this.line('*', strconv.Itoa(len(r)*2))
+		} else {
+			this.line('*', strconv.Itoa(len(r)))
+		}
+		for _, item := range r {
+			if this.proto >= 3 {
+				this.writer.WriteString("*2\r\n")
+			}
+			this.bulk(item.Member)
+			this.double(item.Score)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/wangjia184/sortedset/command"
+)
+
+// Server serves a keyspace of sorted sets over RESP.
+//
+// The keyspace executes commands of all connections one at a time, so every
+// command is atomic like in Redis.
+type Server struct {
+	keyspace *command.Keyspace
+	clients  atomic.Int64
+}
+
+// Create a new Server with an empty keyspace
+func NewServer() *Server {
+	return &Server{
+		keyspace: command.New(),
+	}
+}
+
+// Serve accepts connections on listener until it is closed
+func (this *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go this.serveConn(conn)
+	}
+}
+
+func (this *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	id := this.clients.Add(1)
+	reader := bufio.NewReader(conn)
+	writer := &respWriter{writer: bufio.NewWriter(conn), proto: 2}
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				writer.write(command.Error("ERR Protocol error"))
+				writer.writer.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := false
+		switch strings.ToLower(args[0]) {
+		case "hello":
+			writer.write(this.hello(writer, id, args))
+		case "quit":
+			writer.write(command.Status("OK"))
+			quit = true
+		case "command":
+			// redis-cli asks for the command docs when it starts
+			writer.write(command.Array{})
+		default:
+			writer.write(this.keyspace.Exec(args))
+		}
+
+		// flush once the pipelined commands are answered
+		if quit || reader.Buffered() == 0 {
+			if writer.writer.Flush() != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+// hello switches the protocol version of a connection
+func (this *Server) hello(writer *respWriter, id int64, args []string) command.Reply {
+	if len(args) > 1 {
+		proto, err := strconv.Atoi(args[1])
+		if err != nil {
+			return command.Error("ERR Protocol version is not an integer or out of range")
+		}
+		if proto != 2 && proto != 3 {
+			return command.Error("NOPROTO unsupported protocol version")
+		}
+		writer.proto = proto
+	}
+	return command.Map{
+		command.Bulk("server"), command.Bulk("sortedsetd"),
+		command.Bulk("version"), command.Bulk("1.0.0"),
+		command.Bulk("proto"), command.Integer(writer.proto),
+		command.Bulk("id"), command.Integer(id),
+		command.Bulk("mode"), command.Bulk("standalone"),
+		command.Bulk("role"), command.Bulk("master"),
+		command.Bulk("modules"), command.Array{},
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// client is a minimal RESP client returning the raw text of each reply
+type client struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func startServer(t *testing.T, network string, address string) *client {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go NewServer().Serve(listener)
+	t.Cleanup(func() { listener.Close() })
+
+	conn, err := net.Dial(network, listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &client{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func (this *client) readReply() string {
+	line, err := this.reader.ReadString('\n')
+	if err != nil {
+		this.t.Fatal(err)
+	}
+	switch line[0] {
+	case '*', '%':
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if line[0] == '%' {
+			n *= 2
+		}
+		for i := 0; i < n; i++ {
+			line += this.readReply()
+		}
+	case '$':
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if n >= 0 {
+			data := make([]byte, n+2)
+			if _, err := io.ReadFull(this.reader, data); err != nil {
+				this.t.Fatal(err)
+			}
+			line += string(data)
+		}
+	}
+	return line
+}
+
+func (this *client) do(args ...string) string {
+	var builder strings.Builder
+	builder.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		builder.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	if _, err := this.conn.Write([]byte(builder.String())); err != nil {
+		this.t.Fatal(err)
+	}
+	return this.readReply()
+}
+
+func (this *client) expect(want string, args ...string) {
+	this.t.Helper()
+	want = strings.ReplaceAll(want, "|", "\r\n")
+	if got := this.do(args...); got != want {
+		this.t.Errorf("%v returned %q, expected %q", args, got, want)
+	}
+}
+
+func TestServerRESP2(t *testing.T) {
+	c := startServer(t, "tcp", "127.0.0.1:0")
+
+	c.expect("+PONG|", "PING")
+	c.expect(":4|", "ZADD", "z", "89", "a", "100", "b", "100", "c", "-321", "d")
+	c.expect(":0|", "ZADD", "z", "NX", "1", "a")
+	c.expect(":1|", "ZADD", "z", "XX", "CH", "101", "a")
+	c.expect("$-1|", "ZADD", "z", "GT", "INCR", "-1", "a")
+	c.expect("$3|102|", "ZADD", "z", "INCR", "1", "a")
+	c.expect("-ERR XX and NX options at the same time are not compatible|", "ZADD", "z", "NX", "XX", "1", "a")
+	c.expect("-ERR value is not a valid float|", "ZADD", "z", "x", "a")
+	c.expect(":4|", "ZCARD", "z")
+	c.expect("$4|-321|", "ZSCORE", "z", "d")
+	c.expect("$-1|", "ZSCORE", "z", "missing")
+	c.expect(":3|", "ZRANK", "z", "a")
+	c.expect("*2|:3|$3|102|", "ZRANK", "z", "a", "WITHSCORE")
+
+	c.expect("*4|$1|d|$1|b|$1|c|$1|a|", "ZRANGE", "z", "0", "-1")
+	c.expect("*2|$1|a|$1|c|", "ZRANGE", "z", "0", "1", "REV")
+	c.expect("*0|", "ZRANGE", "z", "3", "1")
+	c.expect("*4|$1|b|$3|100|$1|c|$3|100|", "ZRANGE", "z", "(0", "+inf", "BYSCORE", "LIMIT", "0", "2", "WITHSCORES")
+	c.expect("*2|$1|b|$1|d|", "ZRANGE", "z", "100", "-inf", "BYSCORE", "REV", "LIMIT", "1", "5")
+	c.expect("*0|", "ZRANGE", "z", "100", "-inf", "BYSCORE")
+	c.expect(":2|", "ZCOUNT", "z", "(89", "100")
+	c.expect(":0|", "ZCOUNT", "z", "100", "0")
+
+	c.expect(":3|", "ZADD", "lex", "0", "a", "0", "b", "0", "c")
+	c.expect("*2|$1|b|$1|c|", "ZRANGE", "lex", "(a", "+", "BYLEX")
+	c.expect("*2|$1|c|$1|b|", "ZRANGE", "lex", "+", "[b", "BYLEX", "REV")
+	c.expect("*1|$1|b|", "ZRANGE", "lex", "-", "+", "BYLEX", "LIMIT", "1", "1")
+	c.expect("-ERR min or max not valid string range item|", "ZRANGE", "lex", "a", "+", "BYLEX")
+
+	c.expect("*2|$1|d|$4|-321|", "ZPOPMIN", "z")
+	c.expect("*4|$1|a|$3|102|$1|c|$3|100|", "ZPOPMAX", "z", "2")
+	c.expect(":1|", "ZREM", "z", "b", "missing")
+	c.expect(":0|", "ZCARD", "z")
+	c.expect("*0|", "ZPOPMIN", "z")
+
+	c.expect("-ERR unknown command 'GET'|", "GET", "z")
+	c.expect("-ERR wrong number of arguments for 'zcard' command|", "ZCARD")
+}
+
+func TestServerRESP3(t *testing.T) {
+	c := startServer(t, "unix", filepath.Join(t.TempDir(), "sortedsetd.sock"))
+
+	if reply := c.do("HELLO", "3"); !strings.HasPrefix(reply, "%7\r\n") || !strings.Contains(reply, "$5\r\nproto\r\n:3\r\n") {
+		t.Errorf("HELLO 3 returned %q", reply)
+	}
+	c.expect("-NOPROTO unsupported protocol version|", "HELLO", "4")
+	c.expect(":2|", "ZADD", "z", "1.5", "a", "2", "b")
+	c.expect(",1.5|", "ZSCORE", "z", "a")
+	c.expect("_|", "ZSCORE", "z", "missing")
+	c.expect("*2|*2|$1|a|,1.5|*2|$1|b|,2|", "ZRANGE", "z", "0", "-1", "WITHSCORES")
+	c.expect("*2|$1|b|,2|", "ZPOPMAX", "z")
+	c.expect("*1|*2|$1|a|,1.5|", "ZPOPMIN", "z", "5")
+}
+
+func TestServerPipeline(t *testing.T) {
+	c := startServer(t, "tcp", "127.0.0.1:0")
+
+	// inline commands sent in one write are answered in order
+	if _, err := c.conn.Write([]byte("ZADD z 1 a 2 b\r\nZCARD z\r\nZRANGE z 0 -1\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{":2\r\n", ":2\r\n", "*2\r\n$1\r\na\r\n$1\r\nb\r\n"} {
+		if got := c.readReply(); got != want {
+			t.Errorf("pipelined command returned %q, expected %q", got, want)
+		}
+	}
+}
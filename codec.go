@@ -0,0 +1,263 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"math"
+)
+
+// Codec encodes and decodes the keys, scores or values of a set for
+// WriteTo and ReadFrom. The encoded values are framed by the stream, so
+// Decode always receives exactly the bytes produced by one Append.
+type Codec[T any] interface {
+	Append(buf []byte, value T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// Codecs holds the codecs of a set. A nil field means DefaultCodec.
+type Codecs[K any, SCORE any, V any] struct {
+	Key   Codec[K]
+	Score Codec[SCORE]
+	Value Codec[V]
+}
+
+var errCodecLength = errors.New("sortedset: invalid encoded value length")
+
+// DefaultCodec returns the codec used when none is configured.
+//
+// Strings and byte slices are stored as is, integers as varints, floats and
+// complex numbers by their IEEE 754 bits. Any other type is encoded with
+// encoding/gob, one value at a time. In a snapshot written by WriteTo, the
+// values of other types share one gob encoder for the whole stream instead,
+// so that their type information is written once.
+func DefaultCodec[T any]() Codec[T] {
+	return defaultCodec[T]{}
+}
+
+type defaultCodec[T any] struct{}
+
+func (defaultCodec[T]) Append(buf []byte, value T) ([]byte, error) {
+	if buf, ok := appendBasic(buf, value); ok {
+		return buf, nil
+	}
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(&value); err != nil {
+		return nil, err
+	}
+	return append(buf, b.Bytes()...), nil
+}
+
+func (defaultCodec[T]) Decode(data []byte) (T, error) {
+	if value, ok, err := decodeBasic[T](data); ok {
+		return value, err
+	}
+	var value T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	return value, err
+}
+
+// gobStreamCodec is the default codec of a snapshot stream. It encodes the
+// basic types like defaultCodec, and any other type with one gob encoder, or
+// one gob decoder, for the whole stream. The values must be decoded in the
+// order they were encoded.
+type gobStreamCodec[T any] struct {
+	encoder *gob.Encoder
+	decoder *gob.Decoder
+	buffer  bytes.Buffer // output of encoder, or input of decoder
+}
+
+// streamCodec returns codec, or a new gobStreamCodec if codec is nil
+func streamCodec[T any](codec Codec[T]) Codec[T] {
+	if codec != nil {
+		return codec
+	}
+	return &gobStreamCodec[T]{}
+}
+
+func (this *gobStreamCodec[T]) Append(buf []byte, value T) ([]byte, error) {
+	if buf, ok := appendBasic(buf, value); ok {
+		return buf, nil
+	}
+	if this.encoder == nil {
+		this.encoder = gob.NewEncoder(&this.buffer)
+	}
+	this.buffer.Reset()
+	if err := this.encoder.Encode(&value); err != nil {
+		return nil, err
+	}
+	return append(buf, this.buffer.Bytes()...), nil
+}
+
+func (this *gobStreamCodec[T]) Decode(data []byte) (T, error) {
+	if value, ok, err := decodeBasic[T](data); ok {
+		return value, err
+	}
+	if this.decoder == nil {
+		this.decoder = gob.NewDecoder(&this.buffer)
+	}
+	this.buffer.Reset()
+	this.buffer.Write(data)
+	var value T
+	if err := this.decoder.Decode(&value); err != nil {
+		return value, err
+	}
+	if this.buffer.Len() != 0 {
+		return value, errCodecLength
+	}
+	return value, nil
+}
+
+// appendBasic appends value if it is of a basic type, and reports whether it is
+func appendBasic[T any](buf []byte, value T) ([]byte, bool) {
+	switch v := any(value).(type) {
+	case string:
+		return append(buf, v...), true
+	case []byte:
+		return append(buf, v...), true
+	case bool:
+		if v {
+			return append(buf, 1), true
+		}
+		return append(buf, 0), true
+	case int:
+		return binary.AppendVarint(buf, int64(v)), true
+	case int8:
+		return binary.AppendVarint(buf, int64(v)), true
+	case int16:
+		return binary.AppendVarint(buf, int64(v)), true
+	case int32:
+		return binary.AppendVarint(buf, int64(v)), true
+	case int64:
+		return binary.AppendVarint(buf, v), true
+	case uint:
+		return binary.AppendUvarint(buf, uint64(v)), true
+	case uint8:
+		return binary.AppendUvarint(buf, uint64(v)), true
+	case uint16:
+		return binary.AppendUvarint(buf, uint64(v)), true
+	case uint32:
+		return binary.AppendUvarint(buf, uint64(v)), true
+	case uint64:
+		return binary.AppendUvarint(buf, v), true
+	case uintptr:
+		return binary.AppendUvarint(buf, uint64(v)), true
+	case float32:
+		return binary.LittleEndian.AppendUint32(buf, math.Float32bits(v)), true
+	case float64:
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v)), true
+	case complex64:
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(real(v)))
+		return binary.LittleEndian.AppendUint32(buf, math.Float32bits(imag(v))), true
+	case complex128:
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(real(v)))
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(imag(v))), true
+	}
+	return buf, false
+}
+
+// decodeBasic decodes data if T is a basic type, and reports whether it is
+func decodeBasic[T any](data []byte) (T, bool, error) {
+	var value T
+	var err error
+	varint := func() int64 {
+		v, n := binary.Varint(data)
+		if n != len(data) {
+			err = errCodecLength
+		}
+		return v
+	}
+	uvarint := func() uint64 {
+		v, n := binary.Uvarint(data)
+		if n != len(data) {
+			err = errCodecLength
+		}
+		return v
+	}
+	fixed := func(size int) bool {
+		if len(data) != size {
+			err = errCodecLength
+			return false
+		}
+		return true
+	}
+
+	switch p := any(&value).(type) {
+	case *string:
+		*p = string(data)
+	case *[]byte:
+		*p = bytes.Clone(data)
+	case *bool:
+		if fixed(1) {
+			*p = data[0] != 0
+		}
+	case *int:
+		*p = int(varint())
+	case *int8:
+		*p = int8(varint())
+	case *int16:
+		*p = int16(varint())
+	case *int32:
+		*p = int32(varint())
+	case *int64:
+		*p = varint()
+	case *uint:
+		*p = uint(uvarint())
+	case *uint8:
+		*p = uint8(uvarint())
+	case *uint16:
+		*p = uint16(uvarint())
+	case *uint32:
+		*p = uint32(uvarint())
+	case *uint64:
+		*p = uvarint()
+	case *uintptr:
+		*p = uintptr(uvarint())
+	case *float32:
+		if fixed(4) {
+			*p = math.Float32frombits(binary.LittleEndian.Uint32(data))
+		}
+	case *float64:
+		if fixed(8) {
+			*p = math.Float64frombits(binary.LittleEndian.Uint64(data))
+		}
+	case *complex64:
+		if fixed(8) {
+			*p = complex(math.Float32frombits(binary.LittleEndian.Uint32(data)),
+				math.Float32frombits(binary.LittleEndian.Uint32(data[4:])))
+		}
+	case *complex128:
+		if fixed(16) {
+			*p = complex(math.Float64frombits(binary.LittleEndian.Uint64(data)),
+				math.Float64frombits(binary.LittleEndian.Uint64(data[8:])))
+		}
+	default:
+		return value, false, nil
+	}
+	return value, true, err
+}
@@ -0,0 +1,63 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func exec(t *testing.T, keyspace *Keyspace, line string, want Reply) {
+	t.Helper()
+	if got := keyspace.Exec(strings.Fields(line)); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s returned %#v, expected %#v", line, got, want)
+	}
+}
+
+func TestExec(t *testing.T) {
+	keyspace := New()
+
+	exec(t, keyspace, "ZADD z 89 a 100 b 100 c -321 d", Integer(4))
+	exec(t, keyspace, "ZADD z NX 10 a", Integer(0))
+	exec(t, keyspace, "ZADD z XX CH 101 a", Integer(1))
+	exec(t, keyspace, "ZADD z INCR 1 a", Double(102))
+	exec(t, keyspace, "ZADD z GT INCR -1 a", Nil{})
+	exec(t, keyspace, "ZADD z GT LT 1 a", Error("ERR GT, LT, and/or NX options at the same time are not compatible"))
+	exec(t, keyspace, "ZADD z 1 a 2", Error("ERR syntax error"))
+	exec(t, keyspace, "ZADD z INCR 1 a 2 b", Error("ERR INCR option supports a single increment-element pair"))
+	exec(t, keyspace, "ZADD missing XX 1 a", Integer(0))
+
+	exec(t, keyspace, "ZCARD z", Integer(4))
+	exec(t, keyspace, "ZSCORE z b", Double(100))
+	exec(t, keyspace, "ZSCORE missing b", Nil{})
+	exec(t, keyspace, "ZRANK z d", Integer(0))
+	exec(t, keyspace, "ZRANK z missing", Nil{})
+	exec(t, keyspace, "zrank z a withscore", Array{Integer(3), Double(102)})
+
+	exec(t, keyspace, "ZRANGE z 0 -1", Array{Bulk("d"), Bulk("b"), Bulk("c"), Bulk("a")})
+	exec(t, keyspace, "ZRANGE z -2 -1 REV", Array{Bulk("b"), Bulk("d")})
+	exec(t, keyspace, "ZRANGE z 5 10", Array{})
+	exec(t, keyspace, "ZRANGE z (1 +inf BYSCORE LIMIT 0 10", Array{Bulk("b"), Bulk("c"), Bulk("a")})
+	exec(t, keyspace, "ZRANGE z (100 -inf BYSCORE REV WITHSCORES", ScoredMembers{{"d", -321}})
+	exec(t, keyspace, "ZRANGE z 1 x BYSCORE", Error("ERR min or max is not a float"))
+	exec(t, keyspace, "ZADD e 5 a 5 b 5 c", Integer(3))
+	exec(t, keyspace, "ZRANGE e 5 5 BYSCORE", Array{Bulk("a"), Bulk("b"), Bulk("c")})
+	exec(t, keyspace, "ZRANGE e 5 5 BYSCORE REV", Array{Bulk("c"), Bulk("b"), Bulk("a")})
+	exec(t, keyspace, "ZRANGE e 5 5 BYSCORE LIMIT 0 1", Array{Bulk("a")})
+	exec(t, keyspace, "ZRANGE e 5 5 BYSCORE REV LIMIT 0 1", Array{Bulk("c")})
+	exec(t, keyspace, "ZRANGE e 5 5 BYSCORE REV LIMIT 1 1", Array{Bulk("b")})
+	exec(t, keyspace, "ZREM e a b c", Integer(3))
+	exec(t, keyspace, "ZRANGE z 0 1 LIMIT 0 1", Error("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX"))
+	exec(t, keyspace, "ZCOUNT z -inf (100", Integer(1))
+
+	exec(t, keyspace, "ZPOPMAX z", Array{Bulk("a"), Double(102)})
+	exec(t, keyspace, "ZPOPMIN z 2", ScoredMembers{{"d", -321}, {"b", 100}})
+	exec(t, keyspace, "ZREM z c missing", Integer(1))
+
+	// an empty set is removed from the keyspace
+	if keys := keyspace.Keys(); len(keys) != 0 {
+		t.Errorf("Keys() returned %v, expected none", keys)
+	}
+	exec(t, keyspace, "ZCARD z", Integer(0))
+	exec(t, keyspace, "ZCARD", Error("ERR wrong number of arguments for 'zcard' command"))
+	exec(t, keyspace, "GET z", Error("ERR unknown command 'GET'"))
+}
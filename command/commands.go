@@ -0,0 +1,437 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package command
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	sortedset "github.com/wangjia184/sortedset"
+)
+
+var (
+	errSyntax     = Error("ERR syntax error")
+	errNotFloat   = Error("ERR value is not a valid float")
+	errNotInteger = Error("ERR value is not an integer or out of range")
+	errScoreRange = Error("ERR min or max is not a float")
+	errLexRange   = Error("ERR min or max not valid string range item")
+	errIncrPairs  = Error("ERR INCR option supports a single increment-element pair")
+	errZAddXXNX   = Error("ERR XX and NX options at the same time are not compatible")
+	errZAddGTLTNX = Error("ERR GT, LT, and/or NX options at the same time are not compatible")
+)
+
+// handler is an entry of the command table. A positive arity is the exact
+// number of arguments including the name, a negative one is the minimum.
+type handler struct {
+	arity int
+	fn    func(this *Keyspace, args []string) Reply
+}
+
+var handlers map[string]handler
+
+func init() {
+	handlers = map[string]handler{
+		"ping":    {-1, ping},
+		"zadd":    {-4, zadd},
+		"zrem":    {-3, zrem},
+		"zscore":  {3, zscore},
+		"zrank":   {-3, zrank},
+		"zrange":  {-4, zrange},
+		"zcount":  {4, zcount},
+		"zpopmin": {-2, zpopmin},
+		"zpopmax": {-2, zpopmax},
+		"zcard":   {2, zcard},
+	}
+}
+
+func parseFloat(s string) (float64, error) {
+	score, err := strconv.ParseFloat(s, 64)
+	if err == nil && math.IsNaN(score) {
+		err = strconv.ErrSyntax
+	}
+	return score, err
+}
+
+func parseString(s string) (string, error) {
+	return s, nil
+}
+
+// boundScore maps the infinite bounds onto the float infinities, to compare bounds
+func boundScore(bound sortedset.ScoreBound[float64]) float64 {
+	switch bound.Kind {
+	case sortedset.BoundNegInf:
+		return math.Inf(-1)
+	case sortedset.BoundPosInf:
+		return math.Inf(1)
+	}
+	return bound.Score
+}
+
+func scored(nodes []*sortedset.SortedSetNode[string, float64, []byte]) ScoredMembers {
+	result := make(ScoredMembers, len(nodes))
+	for i, node := range nodes {
+		result[i] = ScoredMember{Member: node.Key(), Score: node.Score()}
+	}
+	return result
+}
+
+func members(nodes []*sortedset.SortedSetNode[string, float64, []byte]) Array {
+	result := make(Array, len(nodes))
+	for i, node := range nodes {
+		result[i] = Bulk(node.Key())
+	}
+	return result
+}
+
+// PING [message]
+func ping(this *Keyspace, args []string) Reply {
+	if len(args) > 1 {
+		return Bulk(args[1])
+	}
+	return Status("PONG")
+}
+
+// ZADD key [NX|XX] [GT|LT] [CH] [INCR] score member [score member ...]
+func zadd(this *Keyspace, args []string) Reply {
+	var options sortedset.ZAddOptions
+	incr := false
+	i := 2
+flags:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			options.NX = true
+		case "XX":
+			options.XX = true
+		case "GT":
+			options.GT = true
+		case "LT":
+			options.LT = true
+		case "CH":
+			options.CH = true
+		case "INCR":
+			incr = true
+		default:
+			break flags
+		}
+	}
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return errSyntax
+	}
+	if options.NX && options.XX {
+		return errZAddXXNX
+	}
+	if (options.GT && options.LT) || ((options.GT || options.LT) && options.NX) {
+		return errZAddGTLTNX
+	}
+	if incr && len(pairs) != 2 {
+		return errIncrPairs
+	}
+	scores := make([]float64, len(pairs)/2)
+	for j := range scores {
+		score, err := parseFloat(pairs[j*2])
+		if err != nil {
+			return errNotFloat
+		}
+		scores[j] = score
+	}
+
+	set := this.lookup(args[1], !options.XX)
+	if set == nil {
+		if incr {
+			return Nil{}
+		}
+		return Integer(0)
+	}
+	defer this.release(args[1])
+
+	if incr {
+		score, ok, err := sortedset.AddIncr(set, pairs[1], scores[0], nil, &options)
+		if err == sortedset.ErrScoreNaN {
+			return Error("ERR resulting score is not a number (NaN)")
+		}
+		if !ok {
+			return Nil{}
+		}
+		return Double(score)
+	}
+
+	count := 0
+	for j, score := range scores {
+		n, _ := set.Add(pairs[j*2+1], score, nil, &options)
+		count += n
+	}
+	return Integer(count)
+}
+
+// ZREM key member [member ...]
+func zrem(this *Keyspace, args []string) Reply {
+	set := this.lookup(args[1], false)
+	if set == nil {
+		return Integer(0)
+	}
+	defer this.release(args[1])
+
+	count := 0
+	for _, member := range args[2:] {
+		if set.Remove(member) != nil {
+			count++
+		}
+	}
+	return Integer(count)
+}
+
+// ZSCORE key member
+func zscore(this *Keyspace, args []string) Reply {
+	set := this.lookup(args[1], false)
+	if set == nil {
+		return Nil{}
+	}
+	node := set.GetByKey(args[2])
+	if node == nil {
+		return Nil{}
+	}
+	return Double(node.Score())
+}
+
+// ZRANK key member [WITHSCORE]
+func zrank(this *Keyspace, args []string) Reply {
+	withScore := false
+	if len(args) == 4 && strings.EqualFold(args[3], "WITHSCORE") {
+		withScore = true
+	} else if len(args) != 3 {
+		return errSyntax
+	}
+
+	set := this.lookup(args[1], false)
+	if set == nil {
+		return Nil{}
+	}
+	rank := set.FindRank(args[2])
+	if rank == 0 {
+		return Nil{}
+	}
+	if withScore {
+		return Array{Integer(rank - 1), Double(set.GetByKey(args[2]).Score())}
+	}
+	return Integer(rank - 1)
+}
+
+// ZRANGE key start stop [BYSCORE|BYLEX] [REV] [LIMIT offset count] [WITHSCORES]
+func zrange(this *Keyspace, args []string) Reply {
+	var byScore, byLex, rev, withScores, limit bool
+	offset, count := 0, -1
+	for i := 4; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "BYSCORE":
+			byScore = true
+		case "BYLEX":
+			byLex = true
+		case "REV":
+			rev = true
+		case "WITHSCORES":
+			withScores = true
+		case "LIMIT":
+			if i+2 >= len(args) {
+				return errSyntax
+			}
+			var err1, err2 error
+			offset, err1 = strconv.Atoi(args[i+1])
+			count, err2 = strconv.Atoi(args[i+2])
+			if err1 != nil || err2 != nil {
+				return errNotInteger
+			}
+			limit = true
+			i += 2
+		default:
+			return errSyntax
+		}
+	}
+	if byScore && byLex {
+		return errSyntax
+	}
+	if limit && !byScore && !byLex {
+		return Error("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+	if withScores && byLex {
+		return Error("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+
+	var nodes []*sortedset.SortedSetNode[string, float64, []byte]
+	switch {
+	case byScore:
+		start, err1 := sortedset.ParseScoreBound(args[2], parseFloat)
+		end, err2 := sortedset.ParseScoreBound(args[3], parseFloat)
+		if err1 != nil || err2 != nil {
+			return errScoreRange
+		}
+		set := this.lookup(args[1], false)
+		// with REV the range is given as max min
+		lo, hi := boundScore(start), boundScore(end)
+		if rev {
+			lo, hi = hi, lo
+		}
+		if set == nil || offset < 0 || lo > hi {
+			return Array{}
+		}
+		options := &sortedset.GetRangeByScoreOptions{Reverse: rev}
+		if count >= 0 {
+			if count == 0 {
+				return Array{}
+			}
+			options.Limit = offset + count
+		}
+		nodes = set.GetRangeByScoreBound(start, end, options)
+	case byLex:
+		start, err1 := sortedset.ParseLexBound(args[2], parseString)
+		end, err2 := sortedset.ParseLexBound(args[3], parseString)
+		if err1 != nil || err2 != nil {
+			return errLexRange
+		}
+		set := this.lookup(args[1], false)
+		// with REV the range is given as max min
+		lo, hi := start, end
+		if rev {
+			lo, hi = end, start
+		}
+		if set == nil || offset < 0 || set.CountByLex(lo, hi) == 0 {
+			return Array{}
+		}
+		options := &sortedset.GetRangeByLexOptions{}
+		if count >= 0 {
+			if count == 0 {
+				return Array{}
+			}
+			options.Limit = offset + count
+		}
+		nodes = set.GetRangeByLex(start, end, options)
+	default:
+		start, err1 := strconv.Atoi(args[2])
+		stop, err2 := strconv.Atoi(args[3])
+		if err1 != nil || err2 != nil {
+			return errNotInteger
+		}
+		set := this.lookup(args[1], false)
+		if set == nil {
+			return Array{}
+		}
+		length := set.GetCount()
+		if start < 0 {
+			start += length
+		}
+		if stop < 0 {
+			stop += length
+		}
+		start = max(start, 0)
+		stop = min(stop, length-1)
+		if start > stop || start >= length {
+			return Array{}
+		}
+		// convert the 0-based indexes to the 1-based ranks of the set
+		if rev {
+			nodes = set.GetRangeByRank(length-start, length-stop, false)
+		} else {
+			nodes = set.GetRangeByRank(start+1, stop+1, false)
+		}
+	}
+
+	if offset >= len(nodes) {
+		return Array{}
+	}
+	nodes = nodes[offset:]
+	if withScores {
+		return scored(nodes)
+	}
+	return members(nodes)
+}
+
+// ZCOUNT key min max
+func zcount(this *Keyspace, args []string) Reply {
+	min, err1 := sortedset.ParseScoreBound(args[2], parseFloat)
+	max, err2 := sortedset.ParseScoreBound(args[3], parseFloat)
+	if err1 != nil || err2 != nil {
+		return errScoreRange
+	}
+	set := this.lookup(args[1], false)
+	if set == nil || boundScore(min) > boundScore(max) {
+		return Integer(0)
+	}
+	return Integer(set.CountByScoreBound(min, max, nil))
+}
+
+// ZPOPMIN key [count]
+func zpopmin(this *Keyspace, args []string) Reply {
+	return pop(this, args, false)
+}
+
+// ZPOPMAX key [count]
+func zpopmax(this *Keyspace, args []string) Reply {
+	return pop(this, args, true)
+}
+
+func pop(this *Keyspace, args []string, fromMax bool) Reply {
+	count := 1
+	if len(args) > 3 {
+		return errSyntax
+	}
+	if len(args) == 3 {
+		n, err := strconv.Atoi(args[2])
+		if err != nil || n < 0 {
+			return Error("ERR value is out of range, must be positive")
+		}
+		count = n
+	}
+
+	set := this.lookup(args[1], false)
+	if set == nil || count == 0 {
+		return Array{}
+	}
+	defer this.release(args[1])
+
+	var nodes []*sortedset.SortedSetNode[string, float64, []byte]
+	for ; count > 0 && set.GetCount() > 0; count-- {
+		if fromMax {
+			nodes = append(nodes, set.PopMax())
+		} else {
+			nodes = append(nodes, set.PopMin())
+		}
+	}
+	if len(args) == 2 {
+		// without count, a single member and score pair is returned flat
+		return Array{Bulk(nodes[0].Key()), Double(nodes[0].Score())}
+	}
+	return scored(nodes)
+}
+
+// ZCARD key
+func zcard(this *Keyspace, args []string) Reply {
+	set := this.lookup(args[1], false)
+	if set == nil {
+		return Integer(0)
+	}
+	return Integer(set.GetCount())
+}
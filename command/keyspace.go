@@ -0,0 +1,106 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package command parses Redis-syntax sorted set commands, such as
+// `ZADD key NX 10 m` or `ZRANGE key (1 +inf BYSCORE LIMIT 0 10`, and executes
+// them against a keyspace of named sorted sets, returning typed replies.
+//
+// It lets tools and test fixtures use the Redis syntax without running Redis.
+// The sortedsetd command serves a Keyspace over the network.
+package command
+
+import (
+	"strings"
+	"sync"
+
+	sortedset "github.com/wangjia184/sortedset"
+)
+
+// ZSet is the type of the sorted sets held by a Keyspace
+type ZSet = sortedset.SortedSet[string, float64, []byte]
+
+// Keyspace holds sorted sets by name.
+//
+// Exec runs commands one at a time under a mutex, so it may be called from
+// any goroutine and every command is atomic like in Redis.
+type Keyspace struct {
+	mutex sync.Mutex
+	sets  map[string]*ZSet
+}
+
+// Create a new empty Keyspace
+func New() *Keyspace {
+	return &Keyspace{
+		sets: make(map[string]*ZSet),
+	}
+}
+
+// Exec parses and executes one command, args[0] being the command name.
+//
+// Invalid commands return an Error reply, like Redis does.
+func (this *Keyspace) Exec(args []string) Reply {
+	if len(args) == 0 {
+		return Error("ERR empty command")
+	}
+	name := strings.ToLower(args[0])
+	cmd, ok := handlers[name]
+	if !ok {
+		return Error("ERR unknown command '" + args[0] + "'")
+	}
+	if (cmd.arity > 0 && len(args) != cmd.arity) || len(args) < -cmd.arity {
+		return Error("ERR wrong number of arguments for '" + name + "' command")
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return cmd.fn(this, args)
+}
+
+// Get the names of the non-empty sets
+func (this *Keyspace) Keys() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	keys := make([]string, 0, len(this.sets))
+	for key := range this.sets {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// lookup returns the set stored at key, creating it if create is true
+func (this *Keyspace) lookup(key string, create bool) *ZSet {
+	set := this.sets[key]
+	if set == nil && create {
+		set = sortedset.New[string, float64, []byte]()
+		this.sets[key] = set
+	}
+	return set
+}
+
+// release deletes the set stored at key once it is empty, like Redis does
+func (this *Keyspace) release(key string) {
+	if set := this.sets[key]; set != nil && set.GetCount() == 0 {
+		delete(this.sets, key)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package command
+
+// Reply is the typed result of a command. It is one of Status, Error, Nil,
+// Bulk, Double, Integer, Array, Map or ScoredMembers, mirroring the RESP3
+// reply types.
+type Reply interface {
+	reply()
+}
+
+// Status is a simple status reply such as OK or PONG
+type Status string
+
+// Error is an error reply, starting with an error code such as ERR
+type Error string
+
+// Nil is the null reply of a missing member or key
+type Nil struct{}
+
+// Bulk is a string reply
+type Bulk string
+
+// Double is a score
+type Double float64
+
+// Integer is a count, a rank or a boolean
+type Integer int64
+
+// Array is an ordered list of replies
+type Array []Reply
+
+// Map is a list of alternating keys and values
+type Map []Reply
+
+// ScoredMembers is the reply of commands returning members WITHSCORES.
+// RESP2 flattens it into alternating members and scores, RESP3 nests pairs.
+type ScoredMembers []ScoredMember
+
+// ScoredMember is a member of a set and its score
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+func (Status) reply()        {}
+func (Error) reply()         {}
+func (Nil) reply()           {}
+func (Bulk) reply()          {}
+func (Double) reply()        {}
+func (Integer) reply()       {}
+func (Array) reply()         {}
+func (Map) reply()           {}
+func (ScoredMembers) reply() {}
+
+// Error implements the error interface
+func (this Error) Error() string {
+	return string(this)
+}
@@ -0,0 +1,447 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"io"
+	"iter"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/constraints"
+)
+
+// ConcurrentSortedSet wraps a SortedSet with a sync.RWMutex so that every
+// method may be called from any goroutine.
+//
+// It has the methods of SortedSet, except the cursors, which cannot be kept
+// across locks, and AddIncr and IncrementScore, which are the functions
+// ConcurrentAddIncr and ConcurrentIncrementScore. Queries (GetRangeByScore,
+// FindRank, Peek*, the iterators, ...) take the read lock and may run in
+// parallel; mutations (AddOrUpdate, Remove, Pop*, GetRangeByRank with remove,
+// the TTL methods, ...) take the write lock.
+//
+// Nodes returned by this type are copies detached from the skip list. Their
+// Value field is a shallow copy of the stored value, so mutating the copy
+// does not affect the set.
+type ConcurrentSortedSet[K constraints.Ordered, SCORE constraints.Ordered, V any] struct {
+	mutex sync.RWMutex
+	set   *SortedSet[K, SCORE, V]
+}
+
+// Create a new ConcurrentSortedSet
+func NewConcurrent[K constraints.Ordered, SCORE constraints.Ordered, V any]() *ConcurrentSortedSet[K, SCORE, V] {
+	return &ConcurrentSortedSet[K, SCORE, V]{
+		set: New[K, SCORE, V](),
+	}
+}
+
+// copyNodes detaches every node in nodes from the skip list.
+func copyNodes[K constraints.Ordered, SCORE constraints.Ordered, V any](nodes []*SortedSetNode[K, SCORE, V]) []*SortedSetNode[K, SCORE, V] {
+	copies := make([]*SortedSetNode[K, SCORE, V], len(nodes))
+	for i, node := range nodes {
+		copies[i] = node.clone()
+	}
+	return copies
+}
+
+// Get the number of elements
+func (this *ConcurrentSortedSet[K, SCORE, V]) GetCount() int {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.GetCount()
+}
+
+// Validate checks the structural invariants of the skip list, see SortedSet.Validate
+func (this *ConcurrentSortedSet[K, SCORE, V]) Validate() error {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.Validate()
+}
+
+// get a copy of the element with minimum score, nil if the set is empty
+func (this *ConcurrentSortedSet[K, SCORE, V]) PeekMin() *SortedSetNode[K, SCORE, V] {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.PeekMin().clone()
+}
+
+// get and remove the element with minimal score, nil if the set is empty
+func (this *ConcurrentSortedSet[K, SCORE, V]) PopMin() *SortedSetNode[K, SCORE, V] {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.PopMin().clone()
+}
+
+// get a copy of the element with maximum score, nil if the set is empty
+func (this *ConcurrentSortedSet[K, SCORE, V]) PeekMax() *SortedSetNode[K, SCORE, V] {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.PeekMax().clone()
+}
+
+// get and remove the element with maximum score, nil if the set is empty
+func (this *ConcurrentSortedSet[K, SCORE, V]) PopMax() *SortedSetNode[K, SCORE, V] {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.PopMax().clone()
+}
+
+// Add an element into the sorted set with specific key / value / score.
+// if the element is added, this method returns true; otherwise false means updated
+func (this *ConcurrentSortedSet[K, SCORE, V]) AddOrUpdate(key K, score SCORE, value V) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.AddOrUpdate(key, score, value)
+}
+
+// Delete element specified by key, returning a copy of the removed node
+func (this *ConcurrentSortedSet[K, SCORE, V]) Remove(key K) *SortedSetNode[K, SCORE, V] {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.Remove(key).clone()
+}
+
+// Has reports whether key is a member of the set.
+func (this *ConcurrentSortedSet[K, SCORE, V]) Has(key K) bool {
+	return this.set.Has(key)
+}
+
+// Get copies of the nodes whose score within the specific range
+//
+// See SortedSet.GetRangeByScore for the meaning of the arguments
+func (this *ConcurrentSortedSet[K, SCORE, V]) GetRangeByScore(start SCORE, end SCORE, options *GetRangeByScoreOptions) []*SortedSetNode[K, SCORE, V] {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return copyNodes(this.set.GetRangeByScore(start, end, options))
+}
+
+// Get copies of the nodes whose score within the range between the bounds start and end
+//
+// See SortedSet.GetRangeByScoreBound for the meaning of the arguments
+func (this *ConcurrentSortedSet[K, SCORE, V]) GetRangeByScoreBound(start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions) []*SortedSetNode[K, SCORE, V] {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return copyNodes(this.set.GetRangeByScoreBound(start, end, options))
+}
+
+// Get copies of the nodes within specific rank range [start, end]
+//
+// If remove is true, the write lock is taken and the nodes are removed.
+// See SortedSet.GetRangeByRank for the meaning of the arguments
+func (this *ConcurrentSortedSet[K, SCORE, V]) GetRangeByRank(start int, end int, remove bool) []*SortedSetNode[K, SCORE, V] {
+	if remove {
+		this.mutex.Lock()
+		defer this.mutex.Unlock()
+	} else {
+		this.mutex.RLock()
+		defer this.mutex.RUnlock()
+	}
+	return copyNodes(this.set.GetRangeByRank(start, end, remove))
+}
+
+// Get a copy of the node by rank.
+//
+// See SortedSet.GetByRank for the meaning of the arguments
+func (this *ConcurrentSortedSet[K, SCORE, V]) GetByRank(rank int, remove bool) *SortedSetNode[K, SCORE, V] {
+	nodes := this.GetRangeByRank(rank, rank, remove)
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return nil
+}
+
+// Get a copy of the node by key
+//
+// If node is not found, nil is returned
+func (this *ConcurrentSortedSet[K, SCORE, V]) GetByKey(key K) *SortedSetNode[K, SCORE, V] {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.GetByKey(key).clone()
+}
+
+// Find the rank of the node specified by key
+//
+// If the node is not found, 0 is returned. Otherwise rank(> 0) is returned
+func (this *ConcurrentSortedSet[K, SCORE, V]) FindRank(key K) int {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.FindRank(key)
+}
+
+// IterFuncRangeByRank apply fn to node within specific rank range [start, end]
+// or until fn return false
+//
+// fn runs while the read lock is held, so it must not call methods of this
+// set that take the write lock.
+func (this *ConcurrentSortedSet[K, SCORE, V]) IterFuncRangeByRank(start int, end int, fn func(key K, value V) bool) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	this.set.IterFuncRangeByRank(start, end, fn)
+}
+
+// Add an element with the semantics of the Redis ZADD command, see SortedSet.Add
+func (this *ConcurrentSortedSet[K, SCORE, V]) Add(key K, score SCORE, value V, options *ZAddOptions) (int, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.Add(key, score, value, options)
+}
+
+// ConcurrentAddIncr adds increment to the score of the element specified by key
+// under the write lock, see AddIncr
+func ConcurrentAddIncr[K constraints.Ordered, SCORE Number, V any](set *ConcurrentSortedSet[K, SCORE, V], key K, increment SCORE, value V, options *ZAddOptions) (SCORE, bool, error) {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	return AddIncr(set.set, key, increment, value, options)
+}
+
+// ConcurrentIncrementScore adds delta to the score of the element specified by
+// key under the write lock, see IncrementScore
+func ConcurrentIncrementScore[K constraints.Ordered, SCORE Number, V any](set *ConcurrentSortedSet[K, SCORE, V], key K, delta SCORE) (SCORE, bool, error) {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	return IncrementScore(set.set, key, delta)
+}
+
+// Get the number of nodes whose score within the specific range, see SortedSet.CountByScore
+func (this *ConcurrentSortedSet[K, SCORE, V]) CountByScore(start SCORE, end SCORE, options *GetRangeByScoreOptions) int {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.CountByScore(start, end, options)
+}
+
+// Get the number of nodes whose score within the range between the bounds
+// start and end, see SortedSet.CountByScoreBound
+func (this *ConcurrentSortedSet[K, SCORE, V]) CountByScoreBound(start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions) int {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.CountByScoreBound(start, end, options)
+}
+
+// Get the number of nodes whose score is less than score, see SortedSet.RankOfScore
+func (this *ConcurrentSortedSet[K, SCORE, V]) RankOfScore(score SCORE) int {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.RankOfScore(score)
+}
+
+// copyingFn returns fn called with copies of the nodes, nil if fn is nil
+func copyingFn[K constraints.Ordered, SCORE constraints.Ordered, V any](fn func(node *SortedSetNode[K, SCORE, V])) func(node *SortedSetNode[K, SCORE, V]) {
+	if fn == nil {
+		return nil
+	}
+	return func(node *SortedSetNode[K, SCORE, V]) {
+		fn(node.clone())
+	}
+}
+
+// Remove the nodes whose score within the specific range, see SortedSet.RemoveRangeByScore
+//
+// fn is called with copies of the removed nodes while the write lock is held,
+// so it must not call methods of this set.
+func (this *ConcurrentSortedSet[K, SCORE, V]) RemoveRangeByScore(start SCORE, end SCORE, options *GetRangeByScoreOptions, fn func(node *SortedSetNode[K, SCORE, V])) int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.RemoveRangeByScore(start, end, options, copyingFn(fn))
+}
+
+// Remove the nodes whose score within the range between the bounds start and
+// end, see SortedSet.RemoveRangeByScoreBound and RemoveRangeByScore
+func (this *ConcurrentSortedSet[K, SCORE, V]) RemoveRangeByScoreBound(start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions, fn func(node *SortedSetNode[K, SCORE, V])) int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.RemoveRangeByScoreBound(start, end, options, copyingFn(fn))
+}
+
+// Get copies of the nodes whose key within the specific range, see SortedSet.GetRangeByLex
+func (this *ConcurrentSortedSet[K, SCORE, V]) GetRangeByLex(start LexBound[K], end LexBound[K], options *GetRangeByLexOptions) []*SortedSetNode[K, SCORE, V] {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return copyNodes(this.set.GetRangeByLex(start, end, options))
+}
+
+// Get the number of nodes whose key within the specific range, see SortedSet.CountByLex
+func (this *ConcurrentSortedSet[K, SCORE, V]) CountByLex(min LexBound[K], max LexBound[K]) int {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.CountByLex(min, max)
+}
+
+// Remove the nodes whose key within the specific range, see SortedSet.RemoveRangeByLex
+func (this *ConcurrentSortedSet[K, SCORE, V]) RemoveRangeByLex(min LexBound[K], max LexBound[K]) int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.RemoveRangeByLex(min, max)
+}
+
+// All returns an iterator over the key/value pairs of the set, see SortedSet.All
+//
+// The read lock is held during the iteration, so the loop body must not call
+// methods of this set that take the write lock.
+func (this *ConcurrentSortedSet[K, SCORE, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		this.mutex.RLock()
+		defer this.mutex.RUnlock()
+		this.set.All()(yield)
+	}
+}
+
+// Backward returns an iterator over the key/value pairs of the set, from the
+// maximum score to the minimum score, see All for the locking
+func (this *ConcurrentSortedSet[K, SCORE, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		this.mutex.RLock()
+		defer this.mutex.RUnlock()
+		this.set.Backward()(yield)
+	}
+}
+
+// RangeByScore returns an iterator over the nodes whose score within the
+// specific range, see SortedSet.RangeByScore and All for the locking
+func (this *ConcurrentSortedSet[K, SCORE, V]) RangeByScore(start SCORE, end SCORE, options *GetRangeByScoreOptions) iter.Seq2[K, V] {
+	return this.RangeByScoreBound(ScoreInclusive(start), ScoreInclusive(end), options)
+}
+
+// RangeByScoreBound returns an iterator over the nodes whose score within the
+// range between the bounds start and end, see SortedSet.RangeByScoreBound and
+// All for the locking
+func (this *ConcurrentSortedSet[K, SCORE, V]) RangeByScoreBound(start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		this.mutex.RLock()
+		defer this.mutex.RUnlock()
+		this.set.RangeByScoreBound(start, end, options)(yield)
+	}
+}
+
+// RangeByRank returns an iterator over the nodes within specific rank range
+// [start, end], see SortedSet.RangeByRank and All for the locking
+func (this *ConcurrentSortedSet[K, SCORE, V]) RangeByRank(start int, end int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		this.mutex.RLock()
+		defer this.mutex.RUnlock()
+		this.set.RangeByRank(start, end)(yield)
+	}
+}
+
+// AddWithTTL adds or updates an element and makes it expire after ttl, see SortedSet.AddWithTTL
+func (this *ConcurrentSortedSet[K, SCORE, V]) AddWithTTL(key K, score SCORE, value V, ttl time.Duration) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.AddWithTTL(key, score, value, ttl)
+}
+
+// Expire sets the time to live of the element specified by key, see SortedSet.Expire
+func (this *ConcurrentSortedSet[K, SCORE, V]) Expire(key K, ttl time.Duration) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.Expire(key, ttl)
+}
+
+// TTL returns the remaining time to live of the element specified by key, see SortedSet.TTL
+func (this *ConcurrentSortedSet[K, SCORE, V]) TTL(key K) (time.Duration, bool) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.TTL(key)
+}
+
+// Persist removes the deadline of the element specified by key, see SortedSet.Persist
+func (this *ConcurrentSortedSet[K, SCORE, V]) Persist(key K) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.Persist(key)
+}
+
+// ExpireCycle removes at most budget expired elements, see SortedSet.ExpireCycle
+func (this *ConcurrentSortedSet[K, SCORE, V]) ExpireCycle(budget int) int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.ExpireCycle(budget)
+}
+
+// SetCapacity bounds the number of elements of the set, see SortedSet.SetCapacity
+//
+// onEvict is called with copies of the evicted nodes while the write lock is
+// held, so it must not call methods of this set.
+func (this *ConcurrentSortedSet[K, SCORE, V]) SetCapacity(capacity int, policy EvictPolicy, onEvict func(node *SortedSetNode[K, SCORE, V])) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.set.SetCapacity(capacity, policy, copyingFn(onEvict))
+}
+
+// Clone returns a read-only copy of the set, see SortedSet.Clone
+func (this *ConcurrentSortedSet[K, SCORE, V]) Clone() *ReadOnly[K, SCORE, V] {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.Clone()
+}
+
+// SetCodecs sets the codecs used by WriteTo, ReadFrom, MarshalBinary and UnmarshalBinary
+func (this *ConcurrentSortedSet[K, SCORE, V]) SetCodecs(codecs Codecs[K, SCORE, V]) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.set.SetCodecs(codecs)
+}
+
+// WriteTo writes a binary snapshot of the set to w, see SortedSet.WriteTo
+func (this *ConcurrentSortedSet[K, SCORE, V]) WriteTo(w io.Writer) (int64, error) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.WriteTo(w)
+}
+
+// ReadFrom replaces the content of the set with a binary snapshot read from r, see SortedSet.ReadFrom
+func (this *ConcurrentSortedSet[K, SCORE, V]) ReadFrom(r io.Reader) (int64, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.ReadFrom(r)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, see SortedSet.MarshalBinary
+func (this *ConcurrentSortedSet[K, SCORE, V]) MarshalBinary() ([]byte, error) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.MarshalBinary()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, see SortedSet.UnmarshalBinary
+func (this *ConcurrentSortedSet[K, SCORE, V]) UnmarshalBinary(data []byte) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.UnmarshalBinary(data)
+}
+
+// MarshalJSON implements json.Marshaler, see SortedSet.MarshalJSON
+func (this *ConcurrentSortedSet[K, SCORE, V]) MarshalJSON() ([]byte, error) {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.set.MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see SortedSet.UnmarshalJSON
+func (this *ConcurrentSortedSet[K, SCORE, V]) UnmarshalJSON(data []byte) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.set.UnmarshalJSON(data)
+}
@@ -0,0 +1,182 @@
+package sortedset
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentSortedSet(t *testing.T) {
+	sortedset := NewConcurrent[string, int64, string]()
+
+	sortedset.AddOrUpdate("a", 89, "Kelly")
+	sortedset.AddOrUpdate("b", 100, "Staley")
+	sortedset.AddOrUpdate("c", 100, "Jordon")
+	sortedset.AddOrUpdate("d", -321, "Park")
+
+	checkOrder(t, sortedset.GetRangeByRank(1, -1, false), []string{"d", "a", "b", "c"})
+	checkOrder(t, sortedset.GetRangeByScore(100, 0, nil), []string{"c", "b", "a"})
+
+	if rank := sortedset.FindRank("a"); rank != 2 {
+		t.Errorf("FindRank() returned %d, expected 2", rank)
+	}
+
+	// returned nodes are copies, mutating them does not touch the set
+	node := sortedset.GetByKey("a")
+	node.Value = "changed"
+	if sortedset.GetByKey("a").Value != "Kelly" {
+		t.Error("GetByKey() returned a live node")
+	}
+
+	if node := sortedset.PopMin(); node == nil || node.Key() != "d" {
+		t.Error("PopMin() does not return expected value `d`")
+	}
+	if node := sortedset.GetByRank(-1, true); node == nil || node.Key() != "c" {
+		t.Error("GetByRank() does not return expected value `c`")
+	}
+	if sortedset.GetCount() != 2 {
+		t.Errorf("GetCount() returned %d, expected 2", sortedset.GetCount())
+	}
+	if sortedset.Remove("missing") != nil || sortedset.GetByKey("missing") != nil {
+		t.Error("unknown key returned a node")
+	}
+}
+
+func TestConcurrentSortedSetWrappers(t *testing.T) {
+	sortedset := NewConcurrent[string, int64, string]()
+	for i, key := range []string{"a", "b", "c", "d", "e"} {
+		sortedset.AddOrUpdate(key, int64(i*10), key)
+	}
+
+	if added, err := sortedset.Add("f", 50, "f", &ZAddOptions{NX: true}); added != 1 || err != nil {
+		t.Errorf("Add() returned %d, %v, expected 1, nil", added, err)
+	}
+	if score, ok, err := ConcurrentAddIncr(sortedset, "a", 5, "a", nil); score != 5 || !ok || err != nil {
+		t.Errorf("ConcurrentAddIncr() returned %d, %v, %v", score, ok, err)
+	}
+	if score, ok, err := ConcurrentIncrementScore(sortedset, "a", 1); score != 6 || !ok || err != nil {
+		t.Errorf("ConcurrentIncrementScore() returned %d, %v, %v", score, ok, err)
+	}
+	if count := sortedset.CountByScore(10, 30, nil); count != 3 {
+		t.Errorf("CountByScore() returned %d, expected 3", count)
+	}
+	if rank := sortedset.RankOfScore(20); rank != 2 {
+		t.Errorf("RankOfScore() returned %d, expected 2", rank)
+	}
+
+	// nodes passed to fn are copies
+	var removed []*SortedSetNode[string, int64, string]
+	sortedset.RemoveRangeByScore(40, 50, nil, func(node *SortedSetNode[string, int64, string]) {
+		removed = append(removed, node)
+	})
+	checkOrder(t, removed, []string{"e", "f"})
+
+	nodes := sortedset.GetRangeByLex(LexInclusive("b"), LexPosInf[string](), nil)
+	checkOrder(t, nodes, []string{"b", "c", "d"})
+	nodes[0].Value = "changed"
+	if sortedset.GetByKey("b").Value != "b" {
+		t.Error("GetRangeByLex() returned a live node")
+	}
+	if count := sortedset.CountByLex(LexNegInf[string](), LexExclusive("c")); count != 2 {
+		t.Errorf("CountByLex() returned %d, expected 2", count)
+	}
+
+	checkSeq(t, sortedset.All(), []string{"a", "b", "c", "d"})
+	checkSeq(t, sortedset.RangeByScore(30, 0, nil), []string{"d", "c", "b", "a"})
+
+	if !sortedset.AddWithTTL("g", 70, "g", time.Hour) {
+		t.Error("AddWithTTL() did not add `g`")
+	}
+	if ttl, ok := sortedset.TTL("g"); !ok || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("TTL() returned %v, %v", ttl, ok)
+	}
+	if !sortedset.Persist("g") {
+		t.Error("Persist() did not remove the deadline of `g`")
+	}
+
+	data, err := sortedset.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied := NewConcurrent[string, int64, string]()
+	if err := copied.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, copied.GetRangeByRank(1, -1, false), []string{"a", "b", "c", "d", "g"})
+
+	var evicted []*SortedSetNode[string, int64, string]
+	sortedset.SetCapacity(3, EvictLowest, func(node *SortedSetNode[string, int64, string]) {
+		evicted = append(evicted, node)
+	})
+	checkOrder(t, evicted, []string{"a", "b"})
+	if removed := sortedset.RemoveRangeByLex(LexNegInf[string](), LexPosInf[string]()); removed != 3 {
+		t.Errorf("RemoveRangeByLex() returned %d, expected 3", removed)
+	}
+}
+
+// run with -race: one writer and several readers exercising every method.
+func TestConcurrentSortedSetRace(t *testing.T) {
+	sortedset := NewConcurrent[int64, int64, int64]()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := int64(0); ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			key := i % 500
+			sortedset.AddOrUpdate(key, i, i)
+			switch i % 11 {
+			case 0:
+				sortedset.Remove(key)
+			case 5:
+				sortedset.PopMin()
+			case 7:
+				sortedset.GetRangeByRank(-2, -1, true)
+			case 9:
+				sortedset.Expire(key, time.Hour)
+			}
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for _, node := range sortedset.GetRangeByScore(0, 1<<40, &GetRangeByScoreOptions{Limit: 10}) {
+					_ = node.Value
+				}
+				sortedset.GetRangeByRank(1, 10, false)
+				sortedset.FindRank(int64(r))
+				sortedset.PeekMin()
+				sortedset.PeekMax()
+				sortedset.GetByKey(int64(r))
+				sortedset.GetCount()
+				sortedset.IterFuncRangeByRank(-1, -5, func(int64, int64) bool { return true })
+				for range sortedset.RangeByRank(1, 10) {
+				}
+				sortedset.CountByScore(0, 1<<40, nil)
+				sortedset.TTL(int64(r))
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		sortedset.Has(int64(i))
+		sortedset.GetByRank(1, false)
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+const (
+	cursorAtNode      = iota // positioned at node
+	cursorBeforeFirst        // positioned before the first node
+	cursorAfterLast          // positioned after the last node
+	cursorGap                // the node at (score, key) was removed or moved
+)
+
+// Cursor is a bidirectional position in a SortedSet.
+//
+// A cursor stays usable when the set is mutated between steps. If the node it
+// points at is removed or its score changes, the cursor is left in the gap
+// where the node used to be: Node returns nil, Next moves to the first node
+// ordered after the old (score, key) position and Prev to the last node
+// ordered before it.
+//
+// Like the set itself, a cursor must only be used from the goroutine owning
+// the set.
+type Cursor[K comparable, SCORE any, V any] struct {
+	set     *SortedSet[K, SCORE, V]
+	node    *SortedSetNode[K, SCORE, V]
+	score   SCORE // position of node
+	key     K
+	state   int
+	version uint64 // set.version when node was last validated
+}
+
+func (this *SortedSet[K, SCORE, V]) newCursor(node *SortedSetNode[K, SCORE, V]) *Cursor[K, SCORE, V] {
+	cursor := &Cursor[K, SCORE, V]{set: this}
+	cursor.moveTo(node, cursorAfterLast)
+	return cursor
+}
+
+// SeekScore returns a cursor at the first node whose score is greater than or
+// equal to score. If there is no such node, the cursor is after the last node.
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet[K, SCORE, V]) SeekScore(score SCORE) *Cursor[K, SCORE, V] {
+	x := this.header
+	for i := this.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			this.scoreCmp(x.level[i].forward.score, score) < 0 {
+			x = x.level[i].forward
+		}
+	}
+	return this.newCursor(this.firstAlive(x.level[0].forward, this.expiredNow()))
+}
+
+// SeekRank returns a cursor at the node with specific rank
+// Note that the rank is 1-based integer. Rank 1 means the first node; Rank -1 means the last node;
+//
+// If rank is beyond the last node, the cursor is after the last node; if it
+// is before the first node, the cursor is before the first node.
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet[K, SCORE, V]) SeekRank(rank int) *Cursor[K, SCORE, V] {
+	now := this.expiredNow()
+	if rank < 0 {
+		rank = this.liveLength(now) + rank + 1
+	}
+	cursor := &Cursor[K, SCORE, V]{set: this}
+	if rank <= 0 {
+		cursor.moveTo(nil, cursorBeforeFirst)
+		return cursor
+	}
+	cursor.moveTo(this.findLiveByRank(rank, now), cursorAfterLast)
+	return cursor
+}
+
+// SeekKey returns a cursor at the node specified by key. If the key is not in
+// the set, the cursor is after the last node.
+//
+// Time complexity of this method is : O(1)
+func (this *SortedSet[K, SCORE, V]) SeekKey(key K) *Cursor[K, SCORE, V] {
+	return this.newCursor(this.get(key))
+}
+
+// moveTo positions the cursor at node, or in state if node is nil
+func (this *Cursor[K, SCORE, V]) moveTo(node *SortedSetNode[K, SCORE, V], state int) {
+	this.version = this.set.version
+	this.node = node
+	if node == nil {
+		this.state = state
+		return
+	}
+	this.state = cursorAtNode
+	this.score = node.score
+	this.key = node.key
+}
+
+// sync detects whether the current node left its position, or expired, since
+// the last step
+func (this *Cursor[K, SCORE, V]) sync() {
+	if this.state == cursorAtNode && this.set.expired(this.node) {
+		this.node = nil
+		this.state = cursorGap
+	}
+	if this.version == this.set.version {
+		return
+	}
+	this.version = this.set.version
+	if this.state == cursorAtNode &&
+		(this.set.lookup(this.key) != this.node || this.set.scoreCmp(this.node.score, this.score) != 0) {
+		this.node = nil
+		this.state = cursorGap
+	}
+}
+
+// Node returns the node at the cursor, nil if the cursor is not at a node
+func (this *Cursor[K, SCORE, V]) Node() *SortedSetNode[K, SCORE, V] {
+	this.sync()
+	return this.node
+}
+
+// Rank returns the rank of the node at the cursor, 0 if the cursor is not at a node
+//
+// Time complexity of this method is : O(log(N))
+func (this *Cursor[K, SCORE, V]) Rank() int {
+	this.sync()
+	if this.node == nil {
+		return 0
+	}
+	return this.set.liveRankOf(this.node)
+}
+
+// Next moves the cursor to the next node in ascending order and reports
+// whether there is one. Past the last node, the cursor is after the last node.
+func (this *Cursor[K, SCORE, V]) Next() bool {
+	this.sync()
+	var x *SortedSetNode[K, SCORE, V]
+	switch this.state {
+	case cursorAtNode:
+		x = this.node.level[0].forward
+	case cursorBeforeFirst:
+		x = this.set.header.level[0].forward
+	case cursorGap:
+		// search the first node after the old position
+		x = this.set.header
+		for i := this.set.level - 1; i >= 0; i-- {
+			for x.level[i].forward != nil &&
+				this.set.compare(x.level[i].forward, this.score, this.key) <= 0 {
+				x = x.level[i].forward
+			}
+		}
+		x = x.level[0].forward
+	}
+	x = this.set.firstAlive(x, this.set.expiredNow())
+	this.moveTo(x, cursorAfterLast)
+	return x != nil
+}
+
+// Prev moves the cursor to the previous node in ascending order and reports
+// whether there is one. Before the first node, the cursor is before the first node.
+func (this *Cursor[K, SCORE, V]) Prev() bool {
+	this.sync()
+	var x *SortedSetNode[K, SCORE, V]
+	switch this.state {
+	case cursorAtNode:
+		x = this.node.backward
+	case cursorAfterLast:
+		x = this.set.tail
+	case cursorGap:
+		// search the last node before the old position
+		x = this.set.header
+		for i := this.set.level - 1; i >= 0; i-- {
+			for x.level[i].forward != nil &&
+				this.set.compare(x.level[i].forward, this.score, this.key) < 0 {
+				x = x.level[i].forward
+			}
+		}
+		if x == this.set.header {
+			x = nil
+		}
+	}
+	x = this.set.lastAlive(x, this.set.expiredNow())
+	this.moveTo(x, cursorBeforeFirst)
+	return x != nil
+}
@@ -0,0 +1,84 @@
+package sortedset
+
+import "testing"
+
+func checkCursor(t *testing.T, cursor *Cursor[string, int64, string], expectedKey string, expectedRank int) {
+	t.Helper()
+	node := cursor.Node()
+	if expectedKey == "" {
+		if node != nil {
+			t.Errorf("cursor is at %q, expected no node", node.Key())
+		}
+	} else if node == nil || node.Key() != expectedKey {
+		t.Errorf("cursor is at %v, expected %q", node, expectedKey)
+	}
+	if rank := cursor.Rank(); rank != expectedRank {
+		t.Errorf("Rank() returned %d, expected %d", rank, expectedRank)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	sortedset := New[string, int64, string]()
+	sortedset.AddOrUpdate("a", 89, "Kelly")
+	sortedset.AddOrUpdate("c", 100, "Jordon")
+	sortedset.AddOrUpdate("d", -321, "Park")
+	sortedset.AddOrUpdate("e", 99, "ntrnrt")
+	sortedset.AddOrUpdate("f", 99, "Lyman")
+	sortedset.AddOrUpdate("h", 70, "Audrey")
+	// d h a e f c
+
+	cursor := sortedset.SeekScore(90)
+	checkCursor(t, cursor, "e", 4)
+	if !cursor.Next() {
+		t.Fatal("Next() returned false")
+	}
+	checkCursor(t, cursor, "f", 5)
+	cursor.Next()
+	if cursor.Next() {
+		t.Error("Next() returned true after the last node")
+	}
+	checkCursor(t, cursor, "", 0)
+	cursor.Prev()
+	checkCursor(t, cursor, "c", 6)
+
+	cursor = sortedset.SeekRank(-4)
+	checkCursor(t, cursor, "a", 3)
+	cursor.Prev()
+	cursor.Prev()
+	if cursor.Prev() {
+		t.Error("Prev() returned true before the first node")
+	}
+	cursor.Next()
+	checkCursor(t, cursor, "d", 1)
+
+	checkCursor(t, sortedset.SeekRank(7), "", 0)
+	checkCursor(t, sortedset.SeekKey("missing"), "", 0)
+	checkCursor(t, sortedset.SeekScore(101), "", 0)
+
+	// removing the current node leaves the cursor in its gap
+	cursor = sortedset.SeekKey("e")
+	sortedset.Remove("e")
+	checkCursor(t, cursor, "", 0)
+	cursor.Next()
+	checkCursor(t, cursor, "f", 4)
+
+	cursor = sortedset.SeekKey("a")
+	sortedset.Remove("a")
+	sortedset.AddOrUpdate("b", 89, "Staley") // inserted into the gap
+	cursor.Prev()
+	checkCursor(t, cursor, "h", 2)
+
+	// moving the current node to a new score also leaves a gap
+	cursor = sortedset.SeekKey("h")
+	sortedset.AddOrUpdate("h", 1000, "Audrey")
+	cursor.Next()
+	checkCursor(t, cursor, "b", 2)
+
+	// unrelated mutations keep the cursor at its node
+	cursor = sortedset.SeekKey("f")
+	sortedset.AddOrUpdate("z", -1000, "Zed")
+	sortedset.PopMax()
+	checkCursor(t, cursor, "f", 4)
+	cursor.Next()
+	checkCursor(t, cursor, "c", 5)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import "iter"
+
+// All returns an iterator over the key/value pairs of the set, from the
+// minimum score to the maximum score
+//
+// The set must not be modified during the iteration
+func (this *SortedSet[K, SCORE, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		now := this.expiredNow()
+		for x := this.firstAlive(this.header.level[0].forward, now); x != nil; x = this.firstAlive(x.level[0].forward, now) {
+			if !yield(x.key, x.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the key/value pairs of the set, from the
+// maximum score to the minimum score
+//
+// The set must not be modified during the iteration
+func (this *SortedSet[K, SCORE, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		now := this.expiredNow()
+		for x := this.lastAlive(this.tail, now); x != nil; x = this.lastAlive(x.backward, now) {
+			if !yield(x.key, x.Value) {
+				return
+			}
+		}
+	}
+}
+
+// RangeByScore returns an iterator over the nodes whose score within the
+// specific range, in the same order as GetRangeByScore returns them
+//
+// If options is nil, it iterates over interval [start, end] without any limit
+// The set must not be modified during the iteration
+func (this *SortedSet[K, SCORE, V]) RangeByScore(start SCORE, end SCORE, options *GetRangeByScoreOptions) iter.Seq2[K, V] {
+	return this.RangeByScoreBound(ScoreInclusive(start), ScoreInclusive(end), options)
+}
+
+// RangeByScoreBound returns an iterator over the nodes whose score within the
+// range between the bounds start and end, in the same order as
+// GetRangeByScoreBound returns them
+//
+// The set must not be modified during the iteration
+func (this *SortedSet[K, SCORE, V]) RangeByScoreBound(start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		this.scanByScore(start, end, options, func(x *SortedSetNode[K, SCORE, V]) bool {
+			return yield(x.key, x.Value)
+		})
+	}
+}
+
+// RangeByRank returns an iterator over the nodes within specific rank range [start, end]
+// Note that the rank is 1-based integer. Rank 1 means the first node; Rank -1 means the last node;
+//
+// If start is greater than end, the nodes are yielded in reserved order
+// The set must not be modified during the iteration
+func (this *SortedSet[K, SCORE, V]) RangeByRank(start int, end int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		this.scanByRank(start, end, func(x *SortedSetNode[K, SCORE, V]) bool {
+			return yield(x.key, x.Value)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package sortedset
+
+import (
+	"iter"
+	"testing"
+)
+
+func checkSeq(t *testing.T, seq iter.Seq2[string, string], expectedOrder []string) {
+	var keys []string
+	for key := range seq {
+		keys = append(keys, key)
+	}
+	if len(expectedOrder) != len(keys) {
+		t.Errorf("keys %v does not contain %d elements", keys, len(expectedOrder))
+		return
+	}
+	for i := 0; i < len(expectedOrder); i++ {
+		if keys[i] != expectedOrder[i] {
+			t.Errorf("keys[%d] is %q, but the expected key is %q", i, keys[i], expectedOrder[i])
+		}
+	}
+
+	// breaking early stops the walk
+	if len(expectedOrder) > 1 {
+		keys = keys[:0]
+		for key := range seq {
+			keys = append(keys, key)
+			break
+		}
+		if len(keys) != 1 || keys[0] != expectedOrder[0] {
+			t.Errorf("break after the first key yielded %v", keys)
+		}
+	}
+}
+
+func TestIterators(t *testing.T) {
+	sortedset := New[string, int64, string]()
+	sortedset.AddOrUpdate("a", 89, "Kelly")
+	sortedset.AddOrUpdate("c", 100, "Jordon")
+	sortedset.AddOrUpdate("d", -321, "Park")
+	sortedset.AddOrUpdate("e", 99, "ntrnrt")
+	sortedset.AddOrUpdate("f", 99, "Lyman")
+	sortedset.AddOrUpdate("g", 99, "Singleton")
+	sortedset.AddOrUpdate("h", 70, "Audrey")
+
+	checkSeq(t, sortedset.All(), []string{"d", "h", "a", "e", "f", "g", "c"})
+	checkSeq(t, sortedset.Backward(), []string{"c", "g", "f", "e", "a", "h", "d"})
+
+	checkSeq(t, sortedset.RangeByRank(2, 4), []string{"h", "a", "e"})
+	checkSeq(t, sortedset.RangeByRank(-2, -3), []string{"g", "f"})
+	checkSeq(t, sortedset.RangeByRank(100, 5), []string{"c", "g", "f"})
+	checkSeq(t, sortedset.RangeByRank(100, 200), nil)
+
+	checkSeq(t, sortedset.RangeByScore(99, 100, nil), []string{"e", "f", "g", "c"})
+	checkSeq(t, sortedset.RangeByScore(100, 99, &GetRangeByScoreOptions{ExcludeStart: true}), []string{"g", "f", "e"})
+	checkSeq(t, sortedset.RangeByScore(100, 50, &GetRangeByScoreOptions{Limit: 2}), []string{"c", "g"})
+	checkSeq(t, sortedset.RangeByScore(-400, -500, nil), nil)
+
+	for key, value := range sortedset.All() {
+		if sortedset.GetByKey(key).Value != value {
+			t.Errorf("All() yielded %q for key %q", value, key)
+		}
+	}
+
+	if n := testing.AllocsPerRun(10, func() {
+		for range sortedset.Backward() {
+		}
+	}); n > 1 {
+		t.Errorf("Backward() allocated %v times", n)
+	}
+}
@@ -0,0 +1,661 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"sync"
+	"time"
+)
+
+// FsyncPolicy tells how often a Journaled set syncs its writer to stable storage.
+// Syncing only happens if the writer has a Sync() error method, like *os.File.
+type FsyncPolicy int
+
+const (
+	FsyncNever       FsyncPolicy = iota // leave it to the operating system
+	FsyncEverySecond                    // sync from a background goroutine once per second
+	FsyncAlways                         // sync after every record
+)
+
+// journal operations
+const (
+	journalAdd byte = iota + 1
+	journalRemove
+	journalRemoveRangeByScore
+	journalRemoveRangeByLex
+	journalRemoveRangeByRank
+	journalExpire
+	journalClock
+)
+
+const journalMaxRecordLength = 1 << 30
+
+var (
+	// ErrJournalCorrupt is returned by Replay for a damaged record that is not the last one
+	ErrJournalCorrupt = errors.New("sortedset: corrupt journal record")
+	// ErrRewriteInProgress is returned by Rewrite while a previous rewrite is running
+	ErrRewriteInProgress = errors.New("sortedset: journal rewrite already in progress")
+)
+
+type syncer interface {
+	Sync() error
+}
+
+// Journaled wraps a SortedSet and records every mutation to an append-only
+// journal before applying it, like the Redis AOF.
+//
+// Each record is framed as a uvarint payload length, the payload and the
+// CRC-32C of the payload. Keys, scores and values are encoded with the codecs
+// of the set at the time NewJournaled is called.
+//
+// PopMin and PopMax are journaled as the removal of the popped key. Like the
+// wrapped set, a Journaled set must be used from a single goroutine; queries
+// can be done directly on Set().
+//
+// Deadlines set by AddWithTTL, Expire and Persist are journaled as absolute
+// times. While some elements have a deadline, every record is preceded by a
+// clock record, and the operation runs at the time of that record, both when
+// it is journaled and when it is replayed, so that Replay expires the same
+// elements. ExpireCycle can be called on Set(), it only removes elements that
+// have expired already.
+type Journaled[K comparable, SCORE any, V any] struct {
+	set    *SortedSet[K, SCORE, V]
+	codecs Codecs[K, SCORE, V]
+	policy FsyncPolicy
+	buf    []byte
+
+	mutex   sync.Mutex // guards the fields below, shared with the background goroutines
+	writer  io.Writer
+	dirty   bool          // written since the last sync
+	rewrite *bytes.Buffer // records written during a rewrite, nil if none is running
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewJournaled returns a set journaling the mutations of set to writer.
+// Close must be called to stop the background sync of FsyncEverySecond.
+func NewJournaled[K comparable, SCORE any, V any](set *SortedSet[K, SCORE, V], writer io.Writer, policy FsyncPolicy) *Journaled[K, SCORE, V] {
+	journaled := &Journaled[K, SCORE, V]{
+		set: set,
+		codecs: Codecs[K, SCORE, V]{
+			Key:   set.keyCodec(),
+			Score: set.scoreCodec(),
+			Value: set.valueCodec(),
+		},
+		policy: policy,
+		writer: writer,
+	}
+	if policy == FsyncEverySecond {
+		journaled.stop = make(chan struct{})
+		journaled.done = make(chan struct{})
+		go journaled.syncEverySecond()
+	}
+	return journaled
+}
+
+// Set returns the wrapped set. It must not be mutated directly, or the
+// journal no longer reproduces it.
+func (this *Journaled[K, SCORE, V]) Set() *SortedSet[K, SCORE, V] {
+	return this.set
+}
+
+func (this *Journaled[K, SCORE, V]) syncEverySecond() {
+	defer close(this.done)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-this.stop:
+			return
+		case <-ticker.C:
+			this.mutex.Lock()
+			this.sync()
+			this.mutex.Unlock()
+		}
+	}
+}
+
+// sync flushes the writer if it was written, the mutex must be held
+func (this *Journaled[K, SCORE, V]) sync() error {
+	if !this.dirty {
+		return nil
+	}
+	this.dirty = false
+	if s, ok := this.writer.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Close stops the background sync and syncs the writer a last time.
+// It does not close the writer.
+func (this *Journaled[K, SCORE, V]) Close() error {
+	if this.stop != nil {
+		close(this.stop)
+		<-this.done
+		this.stop = nil
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.sync()
+}
+
+// appendRecord frames payload as a record
+func appendRecord(buf []byte, payload []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(payload)))
+	buf = append(buf, payload...)
+	return binary.LittleEndian.AppendUint32(buf, crc32.Checksum(payload, castagnoli))
+}
+
+// appendAddPayload appends the payload of the record adding a node
+func appendAddPayload[K comparable, SCORE any, V any](payload []byte, scratch []byte, codecs *Codecs[K, SCORE, V], key K, score SCORE, value V) ([]byte, []byte, error) {
+	payload = append(payload, journalAdd)
+	var err error
+	if payload, scratch, err = appendField(payload, scratch, codecs.Key, key); err != nil {
+		return payload, scratch, err
+	}
+	if payload, scratch, err = appendField(payload, scratch, codecs.Score, score); err != nil {
+		return payload, scratch, err
+	}
+	return appendField(payload, scratch, codecs.Value, value)
+}
+
+// log writes one record before the operation is applied, preceded by a clock
+// record if some elements have a deadline
+//
+// On success the set is pinned to the time of the clock record, and the
+// caller must call unpin once the operation is applied.
+func (this *Journaled[K, SCORE, V]) log(payload []byte) error {
+	var now int64
+	if this.set.expiry != nil && this.set.expiry.length > 0 {
+		now = this.set.now()
+	}
+	return this.logAt(now, payload)
+}
+
+// logAt writes one record like log, preceded by a clock record at now if now is not 0
+func (this *Journaled[K, SCORE, V]) logAt(now int64, payload []byte) error {
+	this.buf = this.buf[:0]
+	if now != 0 {
+		this.buf = appendRecord(this.buf, binary.AppendVarint([]byte{journalClock}, now))
+	}
+	this.buf = appendRecord(this.buf, payload)
+	this.set.pin(now)
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if _, err := this.writer.Write(this.buf); err != nil {
+		this.set.pin(0)
+		return err
+	}
+	if this.rewrite != nil {
+		this.rewrite.Write(this.buf)
+	}
+	this.dirty = true
+	if this.policy == FsyncAlways {
+		if err := this.sync(); err != nil {
+			this.set.pin(0)
+			return err
+		}
+	}
+	return nil
+}
+
+// unpin makes the set use its clock again after an operation is applied
+func (this *Journaled[K, SCORE, V]) unpin() {
+	this.set.pin(0)
+}
+
+// Add an element into the sorted set with specific key / value / score.
+// if the element is added, this method returns true; otherwise false means updated
+//
+// If the record cannot be written, the set is not modified
+func (this *Journaled[K, SCORE, V]) AddOrUpdate(key K, score SCORE, value V) (bool, error) {
+	payload, _, err := appendAddPayload(nil, nil, &this.codecs, key, score, value)
+	if err != nil {
+		return false, err
+	}
+	if err = this.log(payload); err != nil {
+		return false, err
+	}
+	defer this.unpin()
+	return this.set.AddOrUpdate(key, score, value), nil
+}
+
+// AddWithTTL adds or updates an element like AddOrUpdate, and makes it expire
+// after ttl, see SortedSet.AddWithTTL. It is journaled as two records.
+func (this *Journaled[K, SCORE, V]) AddWithTTL(key K, score SCORE, value V, ttl time.Duration) (bool, error) {
+	added, err := this.AddOrUpdate(key, score, value)
+	if err != nil {
+		return false, err
+	}
+	_, err = this.Expire(key, ttl)
+	return added, err
+}
+
+// Expire sets the time to live of the element specified by key, see SortedSet.Expire
+//
+// The deadline is journaled as an absolute time.
+func (this *Journaled[K, SCORE, V]) Expire(key K, ttl time.Duration) (bool, error) {
+	now := this.set.now()
+	if ttl <= 0 {
+		return this.expireAt(now, key, now)
+	}
+	return this.expireAt(now, key, deadlineAfter(now, ttl))
+}
+
+// Persist removes the deadline of the element specified by key, see SortedSet.Persist
+func (this *Journaled[K, SCORE, V]) Persist(key K) (bool, error) {
+	node := this.set.get(key)
+	if node == nil || node.deadline == 0 {
+		return false, nil
+	}
+	return this.expireAt(this.set.now(), key, 0)
+}
+
+// expireAt journals and applies the deadline of the element specified by key
+// at the time now
+func (this *Journaled[K, SCORE, V]) expireAt(now int64, key K, deadline int64) (bool, error) {
+	if !this.set.Has(key) {
+		return false, nil
+	}
+	payload, _, err := appendField([]byte{journalExpire}, nil, this.codecs.Key, key)
+	if err != nil {
+		return false, err
+	}
+	payload = binary.AppendVarint(payload, deadline)
+	if err = this.logAt(now, payload); err != nil {
+		return false, err
+	}
+	defer this.unpin()
+	return this.set.expireAt(key, deadline), nil
+}
+
+func (this *Journaled[K, SCORE, V]) logRemove(key K) error {
+	payload, _, err := appendField([]byte{journalRemove}, nil, this.codecs.Key, key)
+	if err != nil {
+		return err
+	}
+	return this.log(payload)
+}
+
+// Delete element specified by key
+func (this *Journaled[K, SCORE, V]) Remove(key K) (*SortedSetNode[K, SCORE, V], error) {
+	if !this.set.Has(key) {
+		return nil, nil
+	}
+	if err := this.logRemove(key); err != nil {
+		return nil, err
+	}
+	defer this.unpin()
+	return this.set.Remove(key), nil
+}
+
+// get and remove the element with minimal score, nil if the set is empty
+func (this *Journaled[K, SCORE, V]) PopMin() (*SortedSetNode[K, SCORE, V], error) {
+	x := this.set.PeekMin()
+	if x == nil {
+		return nil, nil
+	}
+	return this.Remove(x.key)
+}
+
+// get and remove the element with maximum score, nil if the set is empty
+func (this *Journaled[K, SCORE, V]) PopMax() (*SortedSetNode[K, SCORE, V], error) {
+	x := this.set.PeekMax()
+	if x == nil {
+		return nil, nil
+	}
+	return this.Remove(x.key)
+}
+
+// Remove the nodes whose score within the specific range, see SortedSet.RemoveRangeByScore
+func (this *Journaled[K, SCORE, V]) RemoveRangeByScore(start SCORE, end SCORE, options *GetRangeByScoreOptions, fn func(node *SortedSetNode[K, SCORE, V])) (int, error) {
+	return this.RemoveRangeByScoreBound(ScoreInclusive(start), ScoreInclusive(end), options, fn)
+}
+
+// Remove the nodes whose score within the range between the bounds start and
+// end, see SortedSet.RemoveRangeByScoreBound
+func (this *Journaled[K, SCORE, V]) RemoveRangeByScoreBound(start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions, fn func(node *SortedSetNode[K, SCORE, V])) (int, error) {
+	var limit int
+	if options != nil {
+		limit = max(options.Limit, 0)
+	}
+	start, end = applyExcludes(start, end, options)
+
+	payload := []byte{journalRemoveRangeByScore, byte(start.Kind)}
+	payload, scratch, err := appendField(payload, nil, this.codecs.Score, start.Score)
+	if err != nil {
+		return 0, err
+	}
+	payload = append(payload, byte(end.Kind))
+	if payload, _, err = appendField(payload, scratch, this.codecs.Score, end.Score); err != nil {
+		return 0, err
+	}
+	payload = binary.AppendUvarint(payload, uint64(limit))
+	if err = this.log(payload); err != nil {
+		return 0, err
+	}
+	defer this.unpin()
+	return this.set.RemoveRangeByScoreBound(start, end, &GetRangeByScoreOptions{Limit: limit}, fn), nil
+}
+
+// Remove the nodes whose key within the specific range, see SortedSet.RemoveRangeByLex
+func (this *Journaled[K, SCORE, V]) RemoveRangeByLex(min LexBound[K], max LexBound[K]) (int, error) {
+	payload := []byte{journalRemoveRangeByLex, byte(min.Kind)}
+	payload, scratch, err := appendField(payload, nil, this.codecs.Key, min.Key)
+	if err != nil {
+		return 0, err
+	}
+	payload = append(payload, byte(max.Kind))
+	if payload, _, err = appendField(payload, scratch, this.codecs.Key, max.Key); err != nil {
+		return 0, err
+	}
+	if err = this.log(payload); err != nil {
+		return 0, err
+	}
+	defer this.unpin()
+	return this.set.RemoveRangeByLex(min, max), nil
+}
+
+// Get and remove the nodes within specific rank range [start, end], see SortedSet.GetRangeByRank
+func (this *Journaled[K, SCORE, V]) RemoveRangeByRank(start int, end int) ([]*SortedSetNode[K, SCORE, V], error) {
+	payload := []byte{journalRemoveRangeByRank}
+	payload = binary.AppendVarint(payload, int64(start))
+	payload = binary.AppendVarint(payload, int64(end))
+	if err := this.log(payload); err != nil {
+		return nil, err
+	}
+	defer this.unpin()
+	return this.set.GetRangeByRank(start, end, true), nil
+}
+
+// journalPayload decodes the fields of a record payload
+type journalPayload struct {
+	data []byte
+	err  error
+}
+
+func (this *journalPayload) byte() byte {
+	if this.err != nil || len(this.data) == 0 {
+		this.err = ErrJournalCorrupt
+		return 0
+	}
+	b := this.data[0]
+	this.data = this.data[1:]
+	return b
+}
+
+func (this *journalPayload) uvarint() uint64 {
+	v, n := binary.Uvarint(this.data)
+	if this.err == nil && n <= 0 {
+		this.err = ErrJournalCorrupt
+	}
+	if n > 0 {
+		this.data = this.data[n:]
+	}
+	return v
+}
+
+func (this *journalPayload) varint() int64 {
+	v, n := binary.Varint(this.data)
+	if this.err == nil && n <= 0 {
+		this.err = ErrJournalCorrupt
+	}
+	if n > 0 {
+		this.data = this.data[n:]
+	}
+	return v
+}
+
+func decodeJournalField[T any](payload *journalPayload, codec Codec[T]) T {
+	var value T
+	length := payload.uvarint()
+	if payload.err != nil {
+		return value
+	}
+	if length > uint64(len(payload.data)) {
+		payload.err = ErrJournalCorrupt
+		return value
+	}
+	value, err := codec.Decode(payload.data[:length])
+	if err != nil {
+		payload.err = err
+	}
+	payload.data = payload.data[length:]
+	return value
+}
+
+// apply replays one record payload on the set
+//
+// A clock record pins the set to its time for the following records, until
+// the next clock record or the end of Replay.
+func (this *Journaled[K, SCORE, V]) apply(data []byte) error {
+	payload := &journalPayload{data: data}
+	switch payload.byte() {
+	case journalClock:
+		now := payload.varint()
+		if payload.err == nil {
+			this.set.pin(now)
+		}
+	case journalExpire:
+		key := decodeJournalField(payload, this.codecs.Key)
+		deadline := payload.varint()
+		if payload.err == nil {
+			this.set.expireAt(key, deadline)
+		}
+	case journalAdd:
+		key := decodeJournalField(payload, this.codecs.Key)
+		score := decodeJournalField(payload, this.codecs.Score)
+		value := decodeJournalField(payload, this.codecs.Value)
+		if payload.err == nil {
+			this.set.AddOrUpdate(key, score, value)
+		}
+	case journalRemove:
+		key := decodeJournalField(payload, this.codecs.Key)
+		if payload.err == nil {
+			this.set.Remove(key)
+		}
+	case journalRemoveRangeByLex:
+		min := LexBound[K]{Kind: BoundKind(payload.byte())}
+		min.Key = decodeJournalField(payload, this.codecs.Key)
+		max := LexBound[K]{Kind: BoundKind(payload.byte())}
+		max.Key = decodeJournalField(payload, this.codecs.Key)
+		if payload.err == nil {
+			this.set.RemoveRangeByLex(min, max)
+		}
+	case journalRemoveRangeByScore:
+		start := ScoreBound[SCORE]{Kind: BoundKind(payload.byte())}
+		start.Score = decodeJournalField(payload, this.codecs.Score)
+		end := ScoreBound[SCORE]{Kind: BoundKind(payload.byte())}
+		end.Score = decodeJournalField(payload, this.codecs.Score)
+		options := &GetRangeByScoreOptions{Limit: int(payload.uvarint())}
+		if payload.err == nil {
+			this.set.RemoveRangeByScoreBound(start, end, options, nil)
+		}
+	case journalRemoveRangeByRank:
+		start := payload.varint()
+		end := payload.varint()
+		if payload.err == nil {
+			this.set.GetRangeByRank(int(start), int(end), true)
+		}
+	default:
+		return ErrJournalCorrupt
+	}
+	if payload.err == nil && len(payload.data) != 0 {
+		return ErrJournalCorrupt
+	}
+	return payload.err
+}
+
+// Replay applies the records read from r to the set, without journaling them.
+//
+// A truncated or damaged final record, as left by a crash during a write, is
+// ignored. Replay returns the length of the valid prefix of the journal, so
+// that the caller can truncate the file before appending to it again.
+//
+// The records following a clock record are applied at the time it holds, and
+// the set uses its clock again once Replay returns.
+func (this *Journaled[K, SCORE, V]) Replay(r io.Reader) (int64, error) {
+	defer this.unpin()
+	reader := bufio.NewReader(r)
+	var valid int64
+	var data []byte
+	for {
+		length, err := binary.ReadUvarint(reader)
+		if err == io.EOF {
+			return valid, nil
+		}
+		if err != nil {
+			return valid, ignoreTruncation(err)
+		}
+		if length > journalMaxRecordLength {
+			return valid, this.damaged(reader)
+		}
+		if cap(data) < int(length)+4 {
+			data = make([]byte, int(length)+4)
+		}
+		data = data[:int(length)+4]
+		if _, err = io.ReadFull(reader, data); err != nil {
+			// io.EOF if the journal ends right after the length
+			if err == io.EOF {
+				return valid, nil
+			}
+			return valid, ignoreTruncation(err)
+		}
+		payload := data[:length]
+		if binary.LittleEndian.Uint32(data[length:]) != crc32.Checksum(payload, castagnoli) {
+			return valid, this.damaged(reader)
+		}
+		if err = this.apply(payload); err != nil {
+			return valid, err
+		}
+		valid += int64(uvarintLength(length)) + int64(length) + 4
+	}
+}
+
+// ignoreTruncation treats an unexpected end of the journal as a truncated final record
+func ignoreTruncation(err error) error {
+	if err == io.ErrUnexpectedEOF {
+		return nil
+	}
+	return err
+}
+
+// damaged tells whether a damaged record is the last one of the journal
+func (this *Journaled[K, SCORE, V]) damaged(reader *bufio.Reader) error {
+	if _, err := reader.Peek(1); err == io.EOF {
+		return nil
+	}
+	return ErrJournalCorrupt
+}
+
+func uvarintLength(v uint64) int {
+	return len(binary.AppendUvarint(nil, v))
+}
+
+// Rewrite compacts the journal in the background, like the Redis BGREWRITEAOF.
+//
+// A copy of the set is taken like Clone, without stopping writes, then written
+// to w as one add record per node by a background goroutine, followed by a
+// clock record and one expire record per node with a deadline. Records journaled in
+// the meantime are also buffered, and appended to w once the copy is
+// written. The journal then switches to w, and the returned channel receives
+// nil. The previous writer is not closed. On error, the channel receives the
+// error and the journal keeps using the previous writer.
+func (this *Journaled[K, SCORE, V]) Rewrite(w io.Writer) <-chan error {
+	done := make(chan error, 1)
+
+	this.mutex.Lock()
+	if this.rewrite != nil {
+		this.mutex.Unlock()
+		done <- ErrRewriteInProgress
+		return done
+	}
+	this.rewrite = &bytes.Buffer{}
+	this.mutex.Unlock()
+
+	clone := this.set.newCloneCopier(true).clone
+	go func() {
+		err := this.writeClone(w, clone)
+
+		this.mutex.Lock()
+		if err == nil {
+			_, err = w.Write(this.rewrite.Bytes())
+		}
+		if err == nil {
+			if s, ok := w.(syncer); ok {
+				err = s.Sync()
+			}
+		}
+		if err == nil {
+			this.writer = w
+			this.dirty = false
+		}
+		this.rewrite = nil
+		this.mutex.Unlock()
+
+		done <- err
+	}()
+	return done
+}
+
+func (this *Journaled[K, SCORE, V]) writeClone(w io.Writer, clone *ReadOnly[K, SCORE, V]) error {
+	clone.wait()
+	writer := bufio.NewWriter(w)
+	var buf, payload, scratch []byte
+	var err error
+	for x := clone.set.header.level[0].forward; x != nil; x = x.level[0].forward {
+		if payload, scratch, err = appendAddPayload(payload[:0], scratch, &this.codecs, x.key, x.score, x.Value); err != nil {
+			return err
+		}
+		buf = appendRecord(buf[:0], payload)
+		if _, err = writer.Write(buf); err != nil {
+			return err
+		}
+	}
+	if len(clone.deadlines) != 0 {
+		buf = appendRecord(buf[:0], binary.AppendVarint([]byte{journalClock}, clone.now))
+		if _, err = writer.Write(buf); err != nil {
+			return err
+		}
+	}
+	for _, entry := range clone.deadlines {
+		if payload, scratch, err = appendField(append(payload[:0], journalExpire), scratch, this.codecs.Key, entry.key); err != nil {
+			return err
+		}
+		buf = appendRecord(buf[:0], binary.AppendVarint(payload, entry.deadline))
+		if _, err = writer.Write(buf); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
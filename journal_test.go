@@ -0,0 +1,205 @@
+package sortedset
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// syncBuffer counts the calls to Sync
+type syncBuffer struct {
+	bytes.Buffer
+	syncs int
+}
+
+func (this *syncBuffer) Sync() error {
+	this.syncs++
+	return nil
+}
+
+func checkSameSet(t *testing.T, a *SortedSet[string, int64, string], b *SortedSet[string, int64, string]) {
+	t.Helper()
+	dataA, _ := json.Marshal(a)
+	dataB, _ := json.Marshal(b)
+	if !bytes.Equal(dataA, dataB) {
+		t.Errorf("sets differ:\n%s\n%s", dataA, dataB)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	var journal syncBuffer
+	journaled := NewJournaled(New[string, int64, string](), &journal, FsyncAlways)
+
+	journaled.AddOrUpdate("a", 89, "Kelly")
+	journaled.AddOrUpdate("b", 100, "Staley")
+	journaled.AddOrUpdate("c", 100, "Jordon")
+	journaled.AddOrUpdate("d", -321, "Park")
+	journaled.AddOrUpdate("e", 101, "Albert")
+	journaled.AddOrUpdate("f", 99, "Lyman")
+	journaled.AddOrUpdate("g", 99, "Singleton")
+	journaled.AddOrUpdate("h", 70, "Audrey")
+	journaled.AddOrUpdate("e", 99, "ntrnrt")
+	journaled.Remove("b")
+	journaled.Remove("missing")
+	if node, _ := journaled.PopMin(); node == nil || node.Key() != "d" {
+		t.Error("PopMin() does not return expected value `d`")
+	}
+	journaled.PopMax()
+	journaled.RemoveRangeByScore(99, 100, &GetRangeByScoreOptions{ExcludeEnd: true, Limit: 2}, nil)
+	journaled.RemoveRangeByLex(LexExclusive("z"), LexPosInf[string]())
+	if nodes, _ := journaled.RemoveRangeByRank(1, 1); len(nodes) != 1 || nodes[0].Key() != "h" {
+		t.Error("RemoveRangeByRank() does not return expected value `h`")
+	}
+	journaled.AddOrUpdate("i", 1, "Ivy")
+	if err := journaled.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, journaled.Set().GetRangeByRank(1, -1, false), []string{"i", "a", "g"})
+	if journal.syncs != 16 {
+		t.Errorf("FsyncAlways synced %d times, expected 16", journal.syncs)
+	}
+
+	data := journal.Bytes()
+	replayed := NewJournaled(New[string, int64, string](), &bytes.Buffer{}, FsyncNever)
+	valid, err := replayed.Replay(bytes.NewReader(data))
+	if err != nil || valid != int64(len(data)) {
+		t.Fatalf("Replay() returned %d, %v for %d bytes", valid, err, len(data))
+	}
+	checkSameSet(t, journaled.Set(), replayed.Set())
+
+	// a truncated final record is ignored
+	last := bytes.LastIndexByte(data[:len(data)-5], 0)
+	for _, size := range []int{len(data) - 1, len(data) - 6, last} {
+		replayed = NewJournaled(New[string, int64, string](), &bytes.Buffer{}, FsyncNever)
+		valid, err = replayed.Replay(bytes.NewReader(data[:size]))
+		if err != nil || valid >= int64(size) || replayed.Set().Has("i") {
+			t.Errorf("Replay() of %d bytes returned %d, %v", size, valid, err)
+		}
+	}
+
+	// a damaged record followed by more records is an error
+	corrupted := bytes.Clone(data)
+	corrupted[5] ^= 0xFF
+	replayed = NewJournaled(New[string, int64, string](), &bytes.Buffer{}, FsyncNever)
+	if _, err = replayed.Replay(bytes.NewReader(corrupted)); err != ErrJournalCorrupt {
+		t.Errorf("Replay() of a corrupted journal returned %v", err)
+	}
+}
+
+func TestJournalRewrite(t *testing.T) {
+	var journal bytes.Buffer
+	journaled := NewJournaled(New[string, int64, string](), &journal, FsyncEverySecond)
+	defer journaled.Close()
+
+	for i := 0; i < 100; i++ {
+		journaled.AddOrUpdate("key", int64(i), "overwritten")
+	}
+	journaled.AddOrUpdate("other", 5, "x")
+
+	var rewritten syncBuffer
+	done := journaled.Rewrite(&rewritten)
+	// records written during the rewrite are kept
+	journaled.AddOrUpdate("late", 7, "y")
+	journaled.Remove("other")
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	journaled.AddOrUpdate("after", 8, "z")
+
+	if rewritten.Len() >= journal.Len() {
+		t.Errorf("the rewritten journal has %d bytes, the previous one %d", rewritten.Len(), journal.Len())
+	}
+	if rewritten.syncs != 1 {
+		t.Errorf("Rewrite() synced %d times", rewritten.syncs)
+	}
+
+	replayed := NewJournaled(New[string, int64, string](), &bytes.Buffer{}, FsyncNever)
+	if _, err := replayed.Replay(&rewritten); err != nil {
+		t.Fatal(err)
+	}
+	checkSameSet(t, journaled.Set(), replayed.Set())
+	checkOrder(t, replayed.Set().GetRangeByRank(1, -1, false), []string{"late", "after", "key"})
+}
+
+func TestJournalTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	newSet := func() *SortedSet[string, int64, string] {
+		return NewWithOptions[string, int64, string](&Options{Clock: clock.Now})
+	}
+	var journal bytes.Buffer
+	journaled := NewJournaled(newSet(), &journal, FsyncNever)
+
+	journaled.AddWithTTL("a", 1, "", time.Second)
+	journaled.AddOrUpdate("b", 2, "")
+	journaled.AddWithTTL("c", 3, "", time.Hour)
+	journaled.AddWithTTL("d", 4, "", time.Second)
+	// the update keeps the deadline, a replay after it must too
+	journaled.AddOrUpdate("d", 5, "updated")
+	clock.Advance(2 * time.Second)
+	if nodes, _ := journaled.RemoveRangeByRank(1, 1); len(nodes) != 1 || nodes[0].Key() != "b" {
+		t.Error("RemoveRangeByRank() does not skip expired keys")
+	}
+	journaled.AddOrUpdate("a", 6, "again")
+	journaled.Expire("e", time.Minute)
+	journaled.AddOrUpdate("e", 7, "")
+	journaled.Expire("e", time.Minute)
+	journaled.Persist("c")
+	checkOrder(t, journaled.Set().GetRangeByRank(1, -1, false), []string{"c", "a", "e"})
+
+	var rewritten bytes.Buffer
+	if err := <-journaled.Rewrite(&rewritten); err != nil {
+		t.Fatal(err)
+	}
+
+	// replayed later, the same keys are expired
+	clock.Advance(30 * time.Second)
+	for _, data := range [][]byte{journal.Bytes(), rewritten.Bytes()} {
+		replayed := NewJournaled(newSet(), &bytes.Buffer{}, FsyncNever)
+		if _, err := replayed.Replay(bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		checkSameSet(t, journaled.Set(), replayed.Set())
+		if ttl, ok := replayed.Set().TTL("e"); !ok || ttl != 30*time.Second {
+			t.Errorf("TTL(e) = %v, %v after Replay()", ttl, ok)
+		}
+		if ttl, _ := replayed.Set().TTL("c"); ttl != NoExpiration {
+			t.Errorf("TTL(c) = %v after Replay()", ttl)
+		}
+		if replayed.Set().pinned != 0 {
+			t.Error("the set is still pinned after Replay()")
+		}
+		if err := replayed.Set().Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(time.Minute)
+	checkOrder(t, journaled.Set().GetRangeByRank(1, -1, false), []string{"c", "a"})
+}
+
+func TestJournalTruncatedAtEveryOffset(t *testing.T) {
+	var journal bytes.Buffer
+	journaled := NewJournaled(New[string, int64, string](), &journal, FsyncNever)
+	ends := []int{0}
+	journaled.AddOrUpdate("a", 1, "Kelly")
+	ends = append(ends, journal.Len())
+	journaled.AddOrUpdate("b", 2, "Staley")
+	ends = append(ends, journal.Len())
+	journaled.Remove("a")
+	ends = append(ends, journal.Len())
+
+	data := journal.Bytes()
+	for size := 0; size <= len(data); size++ {
+		expected := 0
+		for _, end := range ends {
+			if end <= size {
+				expected = end
+			}
+		}
+		replayed := NewJournaled(New[string, int64, string](), &bytes.Buffer{}, FsyncNever)
+		valid, err := replayed.Replay(bytes.NewReader(data[:size]))
+		if err != nil || valid != int64(expected) {
+			t.Errorf("Replay() of %d bytes returned %d, %v, expected %d", size, valid, err, expected)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"encoding/json"
+)
+
+// jsonNode is the JSON form of a node
+type jsonNode[K comparable, SCORE any, V any] struct {
+	Key   K     `json:"key"`
+	Score SCORE `json:"score"`
+	Value V     `json:"value"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding the node as {"key", "score", "value"}
+func (this *SortedSetNode[K, SCORE, V]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonNode[K, SCORE, V]{
+		Key:   this.key,
+		Score: this.score,
+		Value: this.Value,
+	})
+}
+
+// MarshalJSON implements json.Marshaler, encoding the set as an array of
+// {"key", "score", "value"} objects in rank order
+func (this *SortedSet[K, SCORE, V]) MarshalJSON() ([]byte, error) {
+	now := this.expiredNow()
+	nodes := make([]jsonNode[K, SCORE, V], 0, this.liveLength(now))
+	for x := this.firstAlive(this.header.level[0].forward, now); x != nil; x = this.firstAlive(x.level[0].forward, now) {
+		nodes = append(nodes, jsonNode[K, SCORE, V]{
+			Key:   x.key,
+			Score: x.score,
+			Value: x.Value,
+		})
+	}
+	return json.Marshal(nodes)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing the content of the set
+// with an array of {"key", "score", "value"} objects.
+//
+// An array in rank order, as produced by MarshalJSON, is loaded in a single
+// pass. Any other order is accepted too, and when a key is repeated the last
+// object wins.
+func (this *SortedSet[K, SCORE, V]) UnmarshalJSON(data []byte) error {
+	var nodes []jsonNode[K, SCORE, V]
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return err
+	}
+
+	this.reset()
+	if this.buildSorted(nodes) {
+		return nil
+	}
+
+	this.reset()
+	for _, node := range nodes {
+		this.AddOrUpdate(node.Key, node.Score, node.Value)
+	}
+	return nil
+}
+
+// buildSorted appends the nodes to the empty set in a single pass, and
+// reports false as soon as a node is out of rank order or repeats a key
+func (this *SortedSet[K, SCORE, V]) buildSorted(nodes []jsonNode[K, SCORE, V]) bool {
+	builder := newSortedSetBuilder(this)
+	for _, node := range nodes {
+		if tail := this.tail; tail != nil && this.compare(tail, node.Score, node.Key) >= 0 {
+			return false
+		}
+		if this.lookup(node.Key) != nil {
+			return false
+		}
+		builder.append(node.Score, node.Key, node.Value)
+	}
+	builder.finish()
+	return true
+}
@@ -0,0 +1,69 @@
+package sortedset
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	sortedset := New[string, int64, string]()
+	sortedset.AddOrUpdate("a", 89, "Kelly")
+	sortedset.AddOrUpdate("b", 100, "Staley")
+	sortedset.AddOrUpdate("d", -321, "Park")
+
+	data, err := json.Marshal(sortedset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"key":"d","score":-321,"value":"Park"},{"key":"a","score":89,"value":"Kelly"},{"key":"b","score":100,"value":"Staley"}]`
+	if string(data) != expected {
+		t.Errorf("json.Marshal() returned %s", data)
+	}
+
+	data, err = json.Marshal(sortedset.GetRangeByRank(1, 2, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `[{"key":"d","score":-321,"value":"Park"},{"key":"a","score":89,"value":"Kelly"}]` {
+		t.Errorf("json.Marshal() of nodes returned %s", data)
+	}
+
+	loaded := New[string, int64, string]()
+	loaded.AddOrUpdate("stale", 1, "")
+	if err := json.Unmarshal([]byte(expected), loaded); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, loaded.GetRangeByRank(1, -1, false), []string{"d", "a", "b"})
+	if loaded.FindRank("b") != 3 || loaded.GetByKey("a").Value != "Kelly" || loaded.Has("stale") {
+		t.Error("json.Unmarshal() did not replace the content")
+	}
+
+	// unsorted input with a repeated key
+	var unsorted SortedSet[string, int64, string]
+	err = json.Unmarshal([]byte(`[{"key":"x","score":5},{"key":"y","score":1},{"key":"x","score":0,"value":"last"}]`), &unsorted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, unsorted.GetRangeByRank(1, -1, false), []string{"x", "y"})
+	if unsorted.GetByKey("x").Value != "last" {
+		t.Error("json.Unmarshal() did not keep the last object of a key")
+	}
+
+	// sorted input with a repeated key that is not adjacent
+	var repeated SortedSet[string, int64, string]
+	err = json.Unmarshal([]byte(`[{"key":"a","score":1},{"key":"b","score":2},{"key":"a","score":3}]`), &repeated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, repeated.GetRangeByRank(1, -1, false), []string{"b", "a"})
+	if err := repeated.Validate(); err != nil {
+		t.Error(err)
+	}
+	if repeated.GetCount() != 2 || repeated.FindRank("a") != 2 || repeated.GetByKey("a").Score() != 3 {
+		t.Error("json.Unmarshal() did not keep the last object of a key")
+	}
+
+	if err := json.Unmarshal([]byte(`{"key":"x"}`), loaded); err == nil {
+		t.Error("json.Unmarshal() accepted an object")
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+// BoundKind tells how a range bound limits the range
+type BoundKind int
+
+const (
+	BoundInclusive BoundKind = iota // the bound value is in the range
+	BoundExclusive                  // the bound value is not in the range
+	BoundNegInf                     // unbounded, before every value
+	BoundPosInf                     // unbounded, after every value
+)
+
+// LexBound is an endpoint of a range of keys, like the "[a", "(a", "-" and "+"
+// arguments of the Redis ZRANGEBYLEX command
+type LexBound[K any] struct {
+	Key  K // ignored for BoundNegInf and BoundPosInf
+	Kind BoundKind
+}
+
+// LexInclusive returns the bound [key
+func LexInclusive[K any](key K) LexBound[K] {
+	return LexBound[K]{Key: key, Kind: BoundInclusive}
+}
+
+// LexExclusive returns the bound (key
+func LexExclusive[K any](key K) LexBound[K] {
+	return LexBound[K]{Key: key, Kind: BoundExclusive}
+}
+
+// LexNegInf returns the bound -, before every key
+func LexNegInf[K any]() LexBound[K] {
+	return LexBound[K]{Kind: BoundNegInf}
+}
+
+// LexPosInf returns the bound +, after every key
+func LexPosInf[K any]() LexBound[K] {
+	return LexBound[K]{Kind: BoundPosInf}
+}
+
+// after reports whether this bound is positioned after other
+func (this LexBound[K]) after(compare func(a, b K) int, other LexBound[K]) bool {
+	if this.Kind == BoundNegInf || other.Kind == BoundPosInf {
+		return false
+	}
+	if this.Kind == BoundPosInf || other.Kind == BoundNegInf {
+		return true
+	}
+	return compare(this.Key, other.Key) > 0
+}
+
+// gteMin reports whether key is on the right side of the min bound
+func (this LexBound[K]) gteMin(compare func(a, b K) int, key K) bool {
+	switch this.Kind {
+	case BoundNegInf:
+		return true
+	case BoundPosInf:
+		return false
+	case BoundExclusive:
+		return compare(key, this.Key) > 0
+	}
+	return compare(key, this.Key) >= 0
+}
+
+// lteMax reports whether key is on the left side of the max bound
+func (this LexBound[K]) lteMax(compare func(a, b K) int, key K) bool {
+	switch this.Kind {
+	case BoundNegInf:
+		return false
+	case BoundPosInf:
+		return true
+	case BoundExclusive:
+		return compare(key, this.Key) < 0
+	}
+	return compare(key, this.Key) <= 0
+}
+
+type GetRangeByLexOptions struct {
+	Limit int // limit the max nodes to return
+}
+
+// Get the nodes whose key within the specific range
+//
+// Like the Redis ZRANGEBYLEX command, the result is only meaningful when all
+// the elements have the same score, so that they are ordered by key.
+// If start is after end, the returned array is in reserved order.
+// If options is nil, it searchs without any limit by default
+//
+// Time complexity of this method is : O(log(N)) to locate start, then O(M) for M returned nodes
+func (this *SortedSet[K, SCORE, V]) GetRangeByLex(start LexBound[K], end LexBound[K], options *GetRangeByLexOptions) []*SortedSetNode[K, SCORE, V] {
+	now := this.expiredNow()
+	var limit int = int((^uint(0)) >> 1)
+	if options != nil && options.Limit > 0 {
+		limit = options.Limit
+	}
+
+	reverse := start.after(this.keyCmp, end)
+	if reverse {
+		start, end = end, start
+	}
+
+	var nodes []*SortedSetNode[K, SCORE, V]
+	x := this.header
+	if reverse { // search from end to start
+		for i := this.level - 1; i >= 0; i-- {
+			for x.level[i].forward != nil &&
+				end.lteMax(this.keyCmp, x.level[i].forward.key) {
+				x = x.level[i].forward
+			}
+		}
+		/* Current node is the last in the range, or the header */
+		for x != nil && x != this.header && limit > 0 && start.gteMin(this.keyCmp, x.key) {
+			if x.aliveAt(now) {
+				nodes = append(nodes, x)
+				limit--
+			}
+			x = x.backward
+		}
+	} else { // search from start to end
+		for i := this.level - 1; i >= 0; i-- {
+			for x.level[i].forward != nil &&
+				!start.gteMin(this.keyCmp, x.level[i].forward.key) {
+				x = x.level[i].forward
+			}
+		}
+		/* Current node is the last before the range */
+		x = x.level[0].forward
+		for x != nil && limit > 0 && end.lteMax(this.keyCmp, x.key) {
+			if x.aliveAt(now) {
+				nodes = append(nodes, x)
+				limit--
+			}
+			x = x.level[0].forward
+		}
+	}
+	return nodes
+}
+
+// Get the number of nodes whose key within the specific range [min, max], like ZLEXCOUNT
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet[K, SCORE, V]) CountByLex(min LexBound[K], max LexBound[K]) int {
+	count := this.countWhile(func(x *SortedSetNode[K, SCORE, V]) bool {
+		return max.lteMax(this.keyCmp, x.key)
+	}) - this.countWhile(func(x *SortedSetNode[K, SCORE, V]) bool {
+		return !min.gteMin(this.keyCmp, x.key)
+	}) - this.countExpired(this.expiredNow(), func(x *SortedSetNode[K, SCORE, V]) bool {
+		return min.gteMin(this.keyCmp, x.key) && max.lteMax(this.keyCmp, x.key)
+	})
+	if count < 0 {
+		return 0
+	}
+	return count
+}
+
+// Remove the nodes whose key within the specific range [min, max], like
+// ZREMRANGEBYLEX, and return the number of removed nodes
+//
+// The expired nodes met in the range are removed too, but not counted.
+//
+// Time complexity of this method is : O(log(N)+M) for M removed nodes
+func (this *SortedSet[K, SCORE, V]) RemoveRangeByLex(min LexBound[K], max LexBound[K]) int {
+	now := this.expiredNow()
+	var update [SKIPLIST_MAXLEVEL]*SortedSetNode[K, SCORE, V]
+
+	x := this.header
+	for i := this.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			!min.gteMin(this.keyCmp, x.level[i].forward.key) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	removed := 0
+	x = x.level[0].forward
+	for x != nil && max.lteMax(this.keyCmp, x.key) {
+		next := x.level[0].forward
+		this.deleteNode(x, update)
+		if x.aliveAt(now) {
+			removed++
+		}
+		x = next
+	}
+	return removed
+}
@@ -0,0 +1,48 @@
+package sortedset
+
+import "testing"
+
+func TestLexRange(t *testing.T) {
+	sortedset := New[string, int64, string]()
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		sortedset.AddOrUpdate(key, 0, "")
+	}
+
+	checkOrder(t, sortedset.GetRangeByLex(LexNegInf[string](), LexInclusive("c"), nil), []string{"a", "b", "c"})
+	checkOrder(t, sortedset.GetRangeByLex(LexNegInf[string](), LexExclusive("c"), nil), []string{"a", "b"})
+	checkOrder(t, sortedset.GetRangeByLex(LexInclusive("aa"), LexExclusive("g"), nil), []string{"b", "c", "d", "e", "f"})
+	checkOrder(t, sortedset.GetRangeByLex(LexExclusive("e"), LexPosInf[string](), nil), []string{"f", "g"})
+	checkOrder(t, sortedset.GetRangeByLex(LexPosInf[string](), LexInclusive("e"), nil), []string{"g", "f", "e"})
+	checkOrder(t, sortedset.GetRangeByLex(LexExclusive("e"), LexNegInf[string](), &GetRangeByLexOptions{Limit: 2}), []string{"d", "c"})
+	checkOrder(t, sortedset.GetRangeByLex(LexNegInf[string](), LexPosInf[string](), &GetRangeByLexOptions{Limit: 2}), []string{"a", "b"})
+	checkOrder(t, sortedset.GetRangeByLex(LexExclusive("c"), LexExclusive("c"), nil), []string{})
+	checkOrder(t, sortedset.GetRangeByLex(LexInclusive("c"), LexInclusive("c"), nil), []string{"c"})
+	checkOrder(t, sortedset.GetRangeByLex(LexInclusive("x"), LexPosInf[string](), nil), []string{})
+	checkOrder(t, sortedset.GetRangeByLex(LexExclusive("0"), LexNegInf[string](), nil), []string{})
+
+	checkCount := func(min LexBound[string], max LexBound[string], expected int) {
+		t.Helper()
+		if count := sortedset.CountByLex(min, max); count != expected {
+			t.Errorf("CountByLex(%v, %v) returned %d, expected %d", min, max, count, expected)
+		}
+	}
+	checkCount(LexNegInf[string](), LexPosInf[string](), 7)
+	checkCount(LexInclusive("b"), LexInclusive("d"), 3)
+	checkCount(LexExclusive("b"), LexExclusive("d"), 1)
+	checkCount(LexInclusive("d"), LexInclusive("b"), 0)
+	checkCount(LexPosInf[string](), LexNegInf[string](), 0)
+
+	if removed := sortedset.RemoveRangeByLex(LexExclusive("b"), LexInclusive("e")); removed != 3 {
+		t.Errorf("RemoveRangeByLex() removed %d nodes, expected 3", removed)
+	}
+	checkOrder(t, sortedset.GetRangeByRank(1, -1, false), []string{"a", "b", "f", "g"})
+	if sortedset.Has("c") || sortedset.FindRank("f") != 3 {
+		t.Error("RemoveRangeByLex() left the set inconsistent")
+	}
+	if removed := sortedset.RemoveRangeByLex(LexInclusive("f"), LexPosInf[string]()); removed != 2 {
+		t.Errorf("RemoveRangeByLex() removed %d nodes, expected 2", removed)
+	}
+	if sortedset.PeekMax().Key() != "b" || sortedset.GetCount() != 2 {
+		t.Error("RemoveRangeByLex() did not update the tail")
+	}
+}
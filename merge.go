@@ -0,0 +1,177 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"container/heap"
+	"iter"
+)
+
+// mergeHead is the next node of one input of a merge
+type mergeHead[K comparable, SCORE any, V any] struct {
+	node  *SortedSetNode[K, SCORE, V]
+	index int // position of the set in the arguments, to break ties
+}
+
+// mergeHeap orders the heads by (score, key, index), descending when reverse is set
+type mergeHeap[K comparable, SCORE any, V any] struct {
+	heads   []mergeHead[K, SCORE, V]
+	reverse bool
+	order   *SortedSet[K, SCORE, V] // the set whose comparison functions are used
+}
+
+func (this *mergeHeap[K, SCORE, V]) Len() int { return len(this.heads) }
+
+func (this *mergeHeap[K, SCORE, V]) Less(i, j int) bool {
+	a, b := this.heads[i], this.heads[j]
+	if c := this.order.compare(a.node, b.node.score, b.node.key); c != 0 {
+		return (c < 0) != this.reverse
+	}
+	return a.index < b.index
+}
+
+func (this *mergeHeap[K, SCORE, V]) Swap(i, j int) {
+	this.heads[i], this.heads[j] = this.heads[j], this.heads[i]
+}
+
+func (this *mergeHeap[K, SCORE, V]) Push(x any) {
+	this.heads = append(this.heads, x.(mergeHead[K, SCORE, V]))
+}
+
+func (this *mergeHeap[K, SCORE, V]) Pop() any {
+	last := this.heads[len(this.heads)-1]
+	this.heads = this.heads[:len(this.heads)-1]
+	return last
+}
+
+// mergeNodes yields the nodes of sets in global (score, key) order, ascending
+// or descending, walking each set lazily along level[0] or the backward pointers
+func mergeNodes[K comparable, SCORE any, V any](reverse bool, sets []*SortedSet[K, SCORE, V], yield func(node *SortedSetNode[K, SCORE, V]) bool) {
+	h := &mergeHeap[K, SCORE, V]{reverse: reverse}
+	nows := make([]int64, len(sets))
+	for i, set := range sets {
+		if i == 0 {
+			h.order = set
+		}
+		nows[i] = set.expiredNow()
+		first := set.firstAlive(set.header.level[0].forward, nows[i])
+		if reverse {
+			first = set.lastAlive(set.tail, nows[i])
+		}
+		if first != nil {
+			h.heads = append(h.heads, mergeHead[K, SCORE, V]{node: first, index: i})
+		}
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		head := &h.heads[0]
+		node := head.node
+		set, now := sets[head.index], nows[head.index]
+		if reverse {
+			head.node = set.lastAlive(node.backward, now)
+		} else {
+			head.node = set.firstAlive(node.level[0].forward, now)
+		}
+		if head.node == nil {
+			heap.Pop(h)
+		} else {
+			heap.Fix(h, 0)
+		}
+
+		if !yield(node) {
+			return
+		}
+	}
+}
+
+// mergeUniqueNodes yields the node chosen by resolve for every key of sets,
+// at the position of the chosen node
+func mergeUniqueNodes[K comparable, SCORE any, V any](reverse bool, resolve func(a, b *SortedSetNode[K, SCORE, V]) *SortedSetNode[K, SCORE, V], sets []*SortedSet[K, SCORE, V], yield func(node *SortedSetNode[K, SCORE, V]) bool) {
+	emitted := make(map[K]struct{})
+	mergeNodes(reverse, sets, func(node *SortedSetNode[K, SCORE, V]) bool {
+		if _, ok := emitted[node.key]; ok {
+			return true
+		}
+		var winner *SortedSetNode[K, SCORE, V]
+		for _, set := range sets {
+			if candidate := set.get(node.key); candidate != nil {
+				if winner == nil {
+					winner = candidate
+				} else {
+					winner = resolve(winner, candidate)
+				}
+			}
+		}
+		// a losing node is skipped, the winner is yielded when the merge reaches it
+		if winner != node {
+			return true
+		}
+		emitted[node.key] = struct{}{}
+		return yield(node)
+	})
+}
+
+// Merge returns an iterator over the nodes of all sets in global ascending
+// (score, key) order, without materializing the union. Nodes with the same
+// score and key are yielded in the order of sets.
+//
+// Nodes are compared with the functions of the first set, so all the sets
+// must have the same order. The sets must not be modified during the iteration.
+//
+// Time complexity of each step is : O(log(K)) for K sets
+func Merge[K comparable, SCORE any, V any](sets ...*SortedSet[K, SCORE, V]) iter.Seq[*SortedSetNode[K, SCORE, V]] {
+	return func(yield func(*SortedSetNode[K, SCORE, V]) bool) {
+		mergeNodes(false, sets, yield)
+	}
+}
+
+// MergeBackward is like Merge, in descending (score, key) order
+func MergeBackward[K comparable, SCORE any, V any](sets ...*SortedSet[K, SCORE, V]) iter.Seq[*SortedSetNode[K, SCORE, V]] {
+	return func(yield func(*SortedSetNode[K, SCORE, V]) bool) {
+		mergeNodes(true, sets, yield)
+	}
+}
+
+// MergeUnique is like Merge, but yields one node per key.
+//
+// When a key is in several sets, resolve is folded over its nodes in the order
+// of sets and must return one of its two arguments. It must be deterministic,
+// since it may be called again for the same key: the chosen node is yielded
+// at its own position in the merge, and the other nodes of the key are skipped.
+//
+// The keys already yielded are remembered until the iteration ends
+func MergeUnique[K comparable, SCORE any, V any](resolve func(a, b *SortedSetNode[K, SCORE, V]) *SortedSetNode[K, SCORE, V], sets ...*SortedSet[K, SCORE, V]) iter.Seq[*SortedSetNode[K, SCORE, V]] {
+	return func(yield func(*SortedSetNode[K, SCORE, V]) bool) {
+		mergeUniqueNodes(false, resolve, sets, yield)
+	}
+}
+
+// MergeUniqueBackward is like MergeUnique, in descending (score, key) order
+func MergeUniqueBackward[K comparable, SCORE any, V any](resolve func(a, b *SortedSetNode[K, SCORE, V]) *SortedSetNode[K, SCORE, V], sets ...*SortedSet[K, SCORE, V]) iter.Seq[*SortedSetNode[K, SCORE, V]] {
+	return func(yield func(*SortedSetNode[K, SCORE, V]) bool) {
+		mergeUniqueNodes(true, resolve, sets, yield)
+	}
+}
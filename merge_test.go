@@ -0,0 +1,71 @@
+package sortedset
+
+import (
+	"iter"
+	"slices"
+	"testing"
+)
+
+func checkNodeSeq(t *testing.T, seq iter.Seq[*SortedSetNode[string, int64, string]], expected []string) {
+	t.Helper()
+	var keys []string
+	for node := range seq {
+		keys = append(keys, node.Key()+":"+node.Value)
+	}
+	if !slices.Equal(keys, expected) {
+		t.Errorf("yielded %v, expected %v", keys, expected)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	shard1 := New[string, int64, string]()
+	shard1.AddOrUpdate("a", 10, "1")
+	shard1.AddOrUpdate("b", 30, "1")
+	shard1.AddOrUpdate("c", 50, "1")
+
+	shard2 := New[string, int64, string]()
+	shard2.AddOrUpdate("b", 20, "2")
+	shard2.AddOrUpdate("d", 30, "2")
+	shard2.AddOrUpdate("e", 60, "2")
+
+	shard3 := New[string, int64, string]()
+	shard3.AddOrUpdate("a", 10, "3")
+
+	checkNodeSeq(t, Merge(shard1, shard2, New[string, int64, string](), shard3),
+		[]string{"a:1", "a:3", "b:2", "b:1", "d:2", "c:1", "e:2"})
+	checkNodeSeq(t, MergeBackward(shard1, shard2, shard3),
+		[]string{"e:2", "c:1", "d:2", "b:1", "b:2", "a:1", "a:3"})
+	checkNodeSeq(t, Merge[string, int64, string](), nil)
+
+	highest := func(a, b *SortedSetNode[string, int64, string]) *SortedSetNode[string, int64, string] {
+		if b.Score() > a.Score() {
+			return b
+		}
+		return a
+	}
+	checkNodeSeq(t, MergeUnique(highest, shard1, shard2, shard3),
+		[]string{"a:1", "b:1", "d:2", "c:1", "e:2"})
+	checkNodeSeq(t, MergeUniqueBackward(highest, shard1, shard2, shard3),
+		[]string{"e:2", "c:1", "d:2", "b:1", "a:1"})
+
+	lowest := func(a, b *SortedSetNode[string, int64, string]) *SortedSetNode[string, int64, string] {
+		if b.Score() < a.Score() {
+			return b
+		}
+		return a
+	}
+	checkNodeSeq(t, MergeUnique(lowest, shard1, shard2, shard3),
+		[]string{"a:1", "b:2", "d:2", "c:1", "e:2"})
+
+	// breaking early stops the merge
+	var keys []string
+	for node := range Merge(shard1, shard2) {
+		keys = append(keys, node.Key())
+		if len(keys) == 2 {
+			break
+		}
+	}
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("break after two nodes yielded %v", keys)
+	}
+}
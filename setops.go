@@ -0,0 +1,212 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"slices"
+)
+
+// Aggregate tells how the scores of a key present in several sets are combined
+type Aggregate int
+
+const (
+	AggregateSum Aggregate = iota // sum of the weighted scores
+	AggregateMin                  // minimum of the weighted scores
+	AggregateMax                  // maximum of the weighted scores
+)
+
+// SetOperationOptions mirrors the WEIGHTS and AGGREGATE arguments of the
+// Redis ZUNIONSTORE and ZINTERSTORE commands
+type SetOperationOptions[SCORE Number, V any] struct {
+	Weights   []SCORE   // the score of each node is multiplied by the weight of its set, 1 for missing weights
+	Aggregate Aggregate // how the weighted scores of a key are combined, AggregateSum by default
+	// MergeValue combines the value accumulated so far for a key with the
+	// value of the next set holding the key, in the order of the sets.
+	// If it is nil, the value of the first set holding the key is kept.
+	MergeValue func(accumulated V, value V) V
+}
+
+// setOperationEntry is a node of the result before the set is built
+type setOperationEntry[K comparable, SCORE Number, V any] struct {
+	key   K
+	score SCORE
+	value V
+}
+
+func (this *SetOperationOptions[SCORE, V]) weight(i int) SCORE {
+	if this == nil || i >= len(this.Weights) {
+		return 1
+	}
+	return this.Weights[i]
+}
+
+// merge folds a weighted score and a value into entry
+func merge[K comparable, SCORE Number, V any](options *SetOperationOptions[SCORE, V], entry *setOperationEntry[K, SCORE, V], score SCORE, value V) {
+	aggregate := AggregateSum
+	if options != nil {
+		aggregate = options.Aggregate
+	}
+	switch aggregate {
+	case AggregateMin:
+		entry.score = min(entry.score, score)
+	case AggregateMax:
+		entry.score = max(entry.score, score)
+	default:
+		entry.score += score
+	}
+	if options != nil && options.MergeValue != nil {
+		entry.value = options.MergeValue(entry.value, value)
+	}
+}
+
+// newSetOperationResult creates an empty result ordered like the first of sets
+func newSetOperationResult[K comparable, SCORE any, V any](sets []*SortedSet[K, SCORE, V]) *SortedSet[K, SCORE, V] {
+	if len(sets) == 0 {
+		return NewFunc[K, SCORE, V](defaultCompare[SCORE](), defaultCompare[K]())
+	}
+	return sets[0].newLike()
+}
+
+// buildSetOperationResult sorts the entries and bulk-builds the result from them
+func buildSetOperationResult[K comparable, SCORE Number, V any](sets []*SortedSet[K, SCORE, V], entries []setOperationEntry[K, SCORE, V]) *SortedSet[K, SCORE, V] {
+	set := newSetOperationResult(sets)
+	slices.SortFunc(entries, func(a, b setOperationEntry[K, SCORE, V]) int {
+		if c := set.scoreCmp(a.score, b.score); c != 0 {
+			return c
+		}
+		return set.keyCmp(a.key, b.key)
+	})
+
+	builder := newSortedSetBuilder(set)
+	for _, entry := range entries {
+		builder.append(entry.score, entry.key, entry.value)
+	}
+	builder.finish()
+	return set
+}
+
+// Union returns a new set with the keys present in any of sets, like ZUNIONSTORE
+//
+// The result is ordered with the comparison functions of the first set.
+// If options is nil, the scores of a key are summed without weights.
+// The result is built from the sorted entries in a single pass instead of
+// inserting every key with AddOrUpdate.
+//
+// Time complexity of this function is : O(N)+O(M*log(M)) for N input nodes and M keys in the result
+func Union[K comparable, SCORE Number, V any](options *SetOperationOptions[SCORE, V], sets ...*SortedSet[K, SCORE, V]) *SortedSet[K, SCORE, V] {
+	var entries []setOperationEntry[K, SCORE, V]
+	index := make(map[K]int)
+	for i, set := range sets {
+		weight := options.weight(i)
+		now := set.expiredNow()
+		for x := set.firstAlive(set.header.level[0].forward, now); x != nil; x = set.firstAlive(x.level[0].forward, now) {
+			if j, ok := index[x.key]; ok {
+				merge(options, &entries[j], x.score*weight, x.Value)
+				continue
+			}
+			index[x.key] = len(entries)
+			entries = append(entries, setOperationEntry[K, SCORE, V]{
+				key:   x.key,
+				score: x.score * weight,
+				value: x.Value,
+			})
+		}
+	}
+	return buildSetOperationResult(sets, entries)
+}
+
+// Intersect returns a new set with the keys present in all of sets, like ZINTERSTORE
+//
+// The result is ordered with the comparison functions of the first set.
+// If options is nil, the scores of a key are summed without weights.
+// The result is built from the sorted entries in a single pass instead of
+// inserting every key with AddOrUpdate.
+//
+// Time complexity of this function is : O(N*K)+O(M*log(M)) for N nodes in the
+// smallest set, K sets and M keys in the result
+func Intersect[K comparable, SCORE Number, V any](options *SetOperationOptions[SCORE, V], sets ...*SortedSet[K, SCORE, V]) *SortedSet[K, SCORE, V] {
+	if len(sets) == 0 {
+		return newSetOperationResult(sets)
+	}
+
+	// probe the other sets with the keys of the smallest one
+	smallest := sets[0]
+	for _, set := range sets[1:] {
+		if set.length < smallest.length {
+			smallest = set
+		}
+	}
+
+	var entries []setOperationEntry[K, SCORE, V]
+	now := smallest.expiredNow()
+next:
+	for x := smallest.firstAlive(smallest.header.level[0].forward, now); x != nil; x = smallest.firstAlive(x.level[0].forward, now) {
+		var entry setOperationEntry[K, SCORE, V]
+		for i, set := range sets {
+			node := set.get(x.key)
+			if node == nil {
+				continue next
+			}
+			if i == 0 {
+				entry = setOperationEntry[K, SCORE, V]{
+					key:   node.key,
+					score: node.score * options.weight(i),
+					value: node.Value,
+				}
+			} else {
+				merge(options, &entry, node.score*options.weight(i), node.Value)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return buildSetOperationResult(sets, entries)
+}
+
+// Diff returns a new set with the nodes of the first set whose key is not in
+// any of the other sets, like ZDIFFSTORE. Scores and values are kept.
+//
+// The first set is already sorted, so the result is built in a single pass.
+//
+// Time complexity of this function is : O(N*K) for N nodes in the first set and K sets
+func Diff[K comparable, SCORE any, V any](sets ...*SortedSet[K, SCORE, V]) *SortedSet[K, SCORE, V] {
+	set := newSetOperationResult(sets)
+	if len(sets) == 0 {
+		return set
+	}
+
+	builder := newSortedSetBuilder(set)
+	now := sets[0].expiredNow()
+next:
+	for x := sets[0].firstAlive(sets[0].header.level[0].forward, now); x != nil; x = sets[0].firstAlive(x.level[0].forward, now) {
+		for _, other := range sets[1:] {
+			if other.Has(x.key) {
+				continue next
+			}
+		}
+		builder.append(x.score, x.key, x.Value)
+	}
+	builder.finish()
+	return set
+}
@@ -0,0 +1,62 @@
+package sortedset
+
+import "testing"
+
+func checkScores(t *testing.T, set *SortedSet[string, int64, string], expected map[string]int64) {
+	t.Helper()
+	if set.GetCount() != len(expected) {
+		t.Errorf("set has %d nodes, expected %d", set.GetCount(), len(expected))
+	}
+	for key, score := range expected {
+		if node := set.GetByKey(key); node == nil || node.Score() != score {
+			t.Errorf("key %q has node %v, expected score %d", key, node, score)
+		}
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	monday := New[string, int64, string]()
+	monday.AddOrUpdate("a", 10, "a1")
+	monday.AddOrUpdate("b", 20, "b1")
+	monday.AddOrUpdate("c", 30, "c1")
+
+	tuesday := New[string, int64, string]()
+	tuesday.AddOrUpdate("b", 5, "b2")
+	tuesday.AddOrUpdate("c", 1, "c2")
+	tuesday.AddOrUpdate("d", 7, "d2")
+
+	union := Union(nil, monday, tuesday)
+	checkScores(t, union, map[string]int64{"a": 10, "b": 25, "c": 31, "d": 7})
+	checkOrder(t, union.GetRangeByRank(1, -1, false), []string{"d", "a", "b", "c"})
+	if union.GetByKey("b").Value != "b1" || union.FindRank("c") != 4 {
+		t.Error("Union() did not keep the first value or build the ranks")
+	}
+
+	union = Union(&SetOperationOptions[int64, string]{
+		Weights:    []int64{1, 10},
+		Aggregate:  AggregateMax,
+		MergeValue: func(accumulated string, value string) string { return accumulated + "+" + value },
+	}, monday, tuesday)
+	checkScores(t, union, map[string]int64{"a": 10, "b": 50, "c": 30, "d": 70})
+	if union.GetByKey("b").Value != "b1+b2" || union.GetByKey("a").Value != "a1" {
+		t.Error("Union() did not merge the values")
+	}
+
+	intersection := Intersect(&SetOperationOptions[int64, string]{Aggregate: AggregateMin}, monday, tuesday)
+	checkScores(t, intersection, map[string]int64{"b": 5, "c": 1})
+	checkOrder(t, intersection.GetRangeByRank(1, -1, false), []string{"c", "b"})
+	if intersection.GetByKey("c").Value != "c1" {
+		t.Error("Intersect() did not keep the value of the first set")
+	}
+	checkScores(t, Intersect(nil, monday, tuesday, New[string, int64, string]()), map[string]int64{})
+
+	difference := Diff(monday, tuesday)
+	checkScores(t, difference, map[string]int64{"a": 10})
+	checkScores(t, Diff(tuesday, monday), map[string]int64{"d": 7})
+	checkScores(t, Diff(monday), map[string]int64{"a": 10, "b": 20, "c": 30})
+
+	// the bulk-built results accept further updates
+	difference.AddOrUpdate("z", 5, "")
+	difference.AddOrUpdate("a", 1, "")
+	checkOrder(t, difference.GetRangeByRank(1, -1, false), []string{"a", "z"})
+}
@@ -25,10 +25,13 @@
 package sortedset
 
 import (
+	"cmp"
+	"math"
 	"math/rand"
+	"slices"
 	"sync"
-
-	"golang.org/x/exp/constraints"
+	"sync/atomic"
+	"time"
 )
 
 const SKIPLIST_MAXLEVEL = 32 /* Should be enough for 2^32 elements */
@@ -41,16 +44,38 @@ const SKIPLIST_P = 0.25      /* Skiplist P = 1/4 */
 // other than the one owning the set. Every other method (AddOrUpdate, Remove,
 // GetRangeByScore, GetRangeByRank, FindRank, Peek/Pop*, GetCount, ...) is not
 // thread-safe and takes no lock: the caller must invoke them from a single
-// goroutine.
-type SortedSet[K constraints.Ordered, SCORE constraints.Ordered, V any] struct {
-	header *SortedSetNode[K, SCORE, V]
-	tail   *SortedSetNode[K, SCORE, V]
-	length int64
-	level  int
-	dict   sync.Map // key K -> *SortedSetNode[K, SCORE, V]
+// goroutine. Use ConcurrentSortedSet when the set is shared between goroutines.
+//
+// Nodes are ordered by scoreCmp, then by keyCmp for equal scores. New uses
+// the natural order of ordered types; NewFunc accepts any comparison functions.
+type SortedSet[K comparable, SCORE any, V any] struct {
+	header   *SortedSetNode[K, SCORE, V]
+	tail     *SortedSetNode[K, SCORE, V]
+	length   int64
+	level    int
+	dict     sync.Map // key K -> *SortedSetNode[K, SCORE, V]
+	version  uint64   // incremented whenever a node is linked or unlinked
+	codecs   Codecs[K, SCORE, V]
+	scoreCmp func(a, b SCORE) int
+	keyCmp   func(a, b K) int
+	levels   *levelGenerator  // nil to use randomLevel
+	clock    func() time.Time // nil to use time.Now
+	// deadlines of the expiring nodes, nil until a deadline is set. The values
+	// are the *SortedSetNode[K, SCORE, V], typed any to end the recursion of
+	// the type parameters
+	expiry *SortedSet[K, int64, any]
+	// time in Unix nanoseconds used instead of the clock while not 0, read
+	// atomically because of Has, see Journaled
+	pinned int64
+	// bound on the number of nodes set by SetCapacity, 0 for none
+	capacity    int
+	evictPolicy EvictPolicy
+	onEvict     func(node *SortedSetNode[K, SCORE, V])
+	// copiers of the clones being built, see Clone
+	clones cloneState[K, SCORE, V]
 }
 
-func createNode[K constraints.Ordered, SCORE constraints.Ordered, V any](level int, score SCORE, key K, value V) *SortedSetNode[K, SCORE, V] {
+func createNode[K comparable, SCORE any, V any](level int, score SCORE, key K, value V) *SortedSetNode[K, SCORE, V] {
 	node := SortedSetNode[K, SCORE, V]{
 		score: score,
 		key:   key,
@@ -75,7 +100,78 @@ func randomLevel() int {
 	return SKIPLIST_MAXLEVEL
 }
 
+// Options configures the skip list of a set
+type Options struct {
+	P            float64     // probability for a node to reach the next level, SKIPLIST_P if not in (0, 1)
+	MaxLevel     int         // highest level of a node, up to SKIPLIST_MAXLEVEL; derived from CapacityHint if 0
+	Rand         rand.Source // source of the node levels, the global math/rand source if nil
+	CapacityHint int         // expected number of elements
+	// Clock tells the time for the deadlines of AddWithTTL and Expire, time.Now if nil.
+	// It must be safe for concurrent use, since Has calls it from other goroutines.
+	Clock func() time.Time
+}
+
+// levelGenerator draws the levels of new nodes with the parameters of Options
+type levelGenerator struct {
+	threshold float64 // P scaled to 0xFFFF
+	maxLevel  int
+	rand      *rand.Rand // nil for the global source
+}
+
+func newLevelGenerator(options *Options) *levelGenerator {
+	p := options.P
+	if p <= 0 || p >= 1 {
+		p = SKIPLIST_P
+	}
+	maxLevel := options.MaxLevel
+	if maxLevel == 0 && options.CapacityHint > 1 {
+		// about one node reaches the highest level once CapacityHint nodes are inserted
+		maxLevel = int(math.Ceil(math.Log(float64(options.CapacityHint))/math.Log(1/p))) + 1
+	}
+	if maxLevel <= 0 || maxLevel > SKIPLIST_MAXLEVEL {
+		maxLevel = SKIPLIST_MAXLEVEL
+	}
+
+	generator := levelGenerator{
+		threshold: p * 0xFFFF,
+		maxLevel:  maxLevel,
+	}
+	if options.Rand != nil {
+		generator.rand = rand.New(options.Rand)
+	}
+	return &generator
+}
+
+// next returns a level between 1 and maxLevel, like randomLevel
+func (this *levelGenerator) next() int {
+	level := 1
+	for level < this.maxLevel {
+		var n int32
+		if this.rand != nil {
+			n = this.rand.Int31()
+		} else {
+			n = rand.Int31()
+		}
+		if float64(n&0xFFFF) >= this.threshold {
+			break
+		}
+		level++
+	}
+	return level
+}
+
+// randomLevel returns the level of a new node of this set
+func (this *SortedSet[K, SCORE, V]) randomLevel() int {
+	if this.levels == nil {
+		return randomLevel()
+	}
+	return this.levels.next()
+}
+
 func (this *SortedSet[K, SCORE, V]) insertNode(score SCORE, key K, value V) *SortedSetNode[K, SCORE, V] {
+	if this.cloning(key) {
+		defer this.unlockClones()
+	}
 	var update [SKIPLIST_MAXLEVEL]*SortedSetNode[K, SCORE, V]
 	var rank [SKIPLIST_MAXLEVEL]int64
 
@@ -88,16 +184,14 @@ func (this *SortedSet[K, SCORE, V]) insertNode(score SCORE, key K, value V) *Sor
 		}
 
 		for x.level[i].forward != nil &&
-			(x.level[i].forward.score < score ||
-				(x.level[i].forward.score == score &&
-					x.level[i].forward.key < key)) {
+			this.compare(x.level[i].forward, score, key) < 0 {
 			rank[i] += x.level[i].span
 			x = x.level[i].forward
 		}
 		update[i] = x
 	}
 
-	level := randomLevel()
+	level := this.randomLevel()
 
 	if level > this.level {
 		for i := this.level; i < level; i++ {
@@ -132,11 +226,67 @@ func (this *SortedSet[K, SCORE, V]) insertNode(score SCORE, key K, value V) *Sor
 		this.tail = x
 	}
 	this.length++
+	this.version++
 	return x
 }
 
+// sortedSetBuilder appends nodes to an empty set in ascending (score, key)
+// order. Each append is O(1), so a set of N sorted nodes is built in O(N)
+// instead of N searches from the header.
+type sortedSetBuilder[K comparable, SCORE any, V any] struct {
+	set  *SortedSet[K, SCORE, V]
+	last [SKIPLIST_MAXLEVEL]*SortedSetNode[K, SCORE, V] // last node on each level
+	rank [SKIPLIST_MAXLEVEL]int64                       // rank of last[i]
+}
+
+func newSortedSetBuilder[K comparable, SCORE any, V any](set *SortedSet[K, SCORE, V]) *sortedSetBuilder[K, SCORE, V] {
+	builder := sortedSetBuilder[K, SCORE, V]{set: set}
+	for i := range builder.last {
+		builder.last[i] = set.header
+	}
+	return &builder
+}
+
+// append adds a node after the current tail, the caller guarantees the order
+func (this *sortedSetBuilder[K, SCORE, V]) append(score SCORE, key K, value V) *SortedSetNode[K, SCORE, V] {
+	set := this.set
+	level := set.randomLevel()
+	if level > set.level {
+		set.level = level
+	}
+
+	x := createNode(level, score, key, value)
+	rank := set.length + 1
+	for i := 0; i < level; i++ {
+		this.last[i].level[i].forward = x
+		this.last[i].level[i].span = rank - this.rank[i]
+		this.last[i] = x
+		this.rank[i] = rank
+	}
+
+	if set.length > 0 {
+		x.backward = set.tail
+	}
+	set.tail = x
+	set.length++
+	set.dict.Store(key, x)
+	return x
+}
+
+// finish sets the spans of the last node on each level, then evicts the
+// nodes beyond the capacity of the set
+func (this *sortedSetBuilder[K, SCORE, V]) finish() {
+	for i := 0; i < this.set.level; i++ {
+		this.last[i].level[i].span = this.set.length - this.rank[i]
+	}
+	this.set.trim()
+}
+
 /* Internal function used by delete, DeleteByScore and DeleteByRank */
 func (this *SortedSet[K, SCORE, V]) deleteNode(x *SortedSetNode[K, SCORE, V], update [SKIPLIST_MAXLEVEL]*SortedSetNode[K, SCORE, V]) {
+	if this.cloning(x.key) {
+		defer this.unlockClones()
+	}
 	for i := 0; i < this.level; i++ {
 		if update[i].level[i].forward == x {
 			update[i].level[i].span += x.level[i].span - 1
@@ -154,7 +304,11 @@ func (this *SortedSet[K, SCORE, V]) deleteNode(x *SortedSetNode[K, SCORE, V], up
 		this.level--
 	}
 	this.length--
+	this.version++
 	this.dict.Delete(x.key)
+	if x.deadline != 0 {
+		this.expiry.Remove(x.key)
+	}
 }
 
 /* Delete an element with matching score/key from the skiplist. */
@@ -164,15 +318,13 @@ func (this *SortedSet[K, SCORE, V]) delete(score SCORE, key K) bool {
 	x := this.header
 	for i := this.level - 1; i >= 0; i-- {
 		for x.level[i].forward != nil &&
-			(x.level[i].forward.score < score ||
-				(x.level[i].forward.score == score &&
-					x.level[i].forward.key < key)) {
+			this.compare(x.level[i].forward, score, key) < 0 {
 			x = x.level[i].forward
 		}
 		update[i] = x
 	}
 	x = x.level[0].forward
-	if x != nil && score == x.score && x.key == key {
+	if x != nil && x.key == key && this.scoreCmp(x.score, score) == 0 {
 		this.deleteNode(x, update)
 		return true
 	}
@@ -180,9 +332,23 @@ func (this *SortedSet[K, SCORE, V]) delete(score SCORE, key K) bool {
 }
 
 // Create a new SortedSet
-func New[K constraints.Ordered, SCORE constraints.Ordered, V any]() *SortedSet[K, SCORE, V] {
+//
+// Keys and scores are ordered by cmp.Compare. Sets of other types are created by NewFunc.
+func New[K cmp.Ordered, SCORE cmp.Ordered, V any]() *SortedSet[K, SCORE, V] {
+	return NewFunc[K, SCORE, V](cmp.Compare[SCORE], cmp.Compare[K])
+}
+
+// Create a new SortedSet ordered by scoreCmp, then by keyCmp for equal scores
+//
+// Both functions return a negative number when a < b, a positive number when
+// a > b and zero when a == b, like cmp.Compare. They allow scores and keys
+// such as time.Time, *big.Int or structs, which are not ordered types. keyCmp
+// must return zero only for equal keys.
+func NewFunc[K comparable, SCORE any, V any](scoreCmp func(a, b SCORE) int, keyCmp func(a, b K) int) *SortedSet[K, SCORE, V] {
 	sortedSet := SortedSet[K, SCORE, V]{
-		level: 1,
+		level:    1,
+		scoreCmp: scoreCmp,
+		keyCmp:   keyCmp,
 	}
 	var emptyKey K
 	var emptyScore SCORE
@@ -191,21 +357,131 @@ func New[K constraints.Ordered, SCORE constraints.Ordered, V any]() *SortedSet[K
 	return &sortedSet
 }
 
+// Create a new SortedSet with specific options
+//
+// If options is nil, it is the same as New
+func NewWithOptions[K cmp.Ordered, SCORE cmp.Ordered, V any](options *Options) *SortedSet[K, SCORE, V] {
+	return NewFuncWithOptions[K, SCORE, V](cmp.Compare[SCORE], cmp.Compare[K], options)
+}
+
+// Create a new SortedSet ordered by scoreCmp and keyCmp, with specific options
+//
+// See NewFunc and NewWithOptions
+func NewFuncWithOptions[K comparable, SCORE any, V any](scoreCmp func(a, b SCORE) int, keyCmp func(a, b K) int, options *Options) *SortedSet[K, SCORE, V] {
+	sortedSet := NewFunc[K, SCORE, V](scoreCmp, keyCmp)
+	if options != nil {
+		sortedSet.levels = newLevelGenerator(options)
+		sortedSet.clock = options.Clock
+	}
+	return sortedSet
+}
+
+// newLike creates an empty set with the comparison functions and the options of this set
+//
+// The new set draws its levels from the global source, since a rand.Source
+// cannot be shared by sets used from different goroutines.
+func (this *SortedSet[K, SCORE, V]) newLike() *SortedSet[K, SCORE, V] {
+	sortedSet := NewFunc[K, SCORE, V](this.scoreCmp, this.keyCmp)
+	if this.levels != nil {
+		levels := *this.levels
+		levels.rand = nil
+		sortedSet.levels = &levels
+	}
+	sortedSet.clock = this.clock
+	return sortedSet
+}
+
+// defaultCompare returns the comparison function New uses for T, so that a
+// zero SortedSet of predeclared ordered types works as if it was created by
+// New. It returns nil for any other type, including named types such as
+// time.Duration: their sets must be created by New or NewFunc.
+func defaultCompare[T any]() func(a, b T) int {
+	var compare any
+	switch any(*new(T)).(type) {
+	case string:
+		compare = cmp.Compare[string]
+	case int:
+		compare = cmp.Compare[int]
+	case int8:
+		compare = cmp.Compare[int8]
+	case int16:
+		compare = cmp.Compare[int16]
+	case int32:
+		compare = cmp.Compare[int32]
+	case int64:
+		compare = cmp.Compare[int64]
+	case uint:
+		compare = cmp.Compare[uint]
+	case uint8:
+		compare = cmp.Compare[uint8]
+	case uint16:
+		compare = cmp.Compare[uint16]
+	case uint32:
+		compare = cmp.Compare[uint32]
+	case uint64:
+		compare = cmp.Compare[uint64]
+	case uintptr:
+		compare = cmp.Compare[uintptr]
+	case float32:
+		compare = cmp.Compare[float32]
+	case float64:
+		compare = cmp.Compare[float64]
+	default:
+		return nil
+	}
+	return compare.(func(a, b T) int)
+}
+
+// compare orders the node x against the position (score, key)
+func (this *SortedSet[K, SCORE, V]) compare(x *SortedSetNode[K, SCORE, V], score SCORE, key K) int {
+	if c := this.scoreCmp(x.score, score); c != 0 {
+		return c
+	}
+	return this.keyCmp(x.key, key)
+}
+
+// reset removes all the elements
+//
+// A zero SortedSet gets the comparison functions of New, see defaultCompare.
+// It panics if the key or the score type has none.
+func (this *SortedSet[K, SCORE, V]) reset() {
+	if this.scoreCmp == nil {
+		this.scoreCmp = defaultCompare[SCORE]()
+	}
+	if this.keyCmp == nil {
+		this.keyCmp = defaultCompare[K]()
+	}
+	if this.scoreCmp == nil || this.keyCmp == nil {
+		panic("sortedset: no default order for the key or score type of a zero SortedSet, create it with New or NewFunc")
+	}
+	var emptyKey K
+	var emptyScore SCORE
+	var emptyValue V
+	this.detachClones()
+	this.header = createNode(SKIPLIST_MAXLEVEL, emptyScore, emptyKey, emptyValue)
+	this.tail = nil
+	this.length = 0
+	this.level = 1
+	this.version++
+	this.dict.Clear()
+	this.expiry = nil
+}
+
 // Get the number of elements
 func (this *SortedSet[K, SCORE, V]) GetCount() int {
-	return int(this.length)
+	return this.liveLength(this.expiredNow())
 }
 
 // get the element with minimum score, nil if the set is empty
 // Time complexity of this method is : O(log(N))
 func (this *SortedSet[K, SCORE, V]) PeekMin() *SortedSetNode[K, SCORE, V] {
-	return this.header.level[0].forward
+	return this.firstAlive(this.header.level[0].forward, this.expiredNow())
 }
 
 // get and remove the element with minimal score, nil if the set is empty
 // Time complexity of this method is : O(log(N))
 func (this *SortedSet[K, SCORE, V]) PopMin() *SortedSetNode[K, SCORE, V] {
-	x := this.header.level[0].forward
+	x := this.PeekMin()
 	if x != nil {
 		this.Remove(x.key)
 	}
@@ -215,13 +491,13 @@ func (this *SortedSet[K, SCORE, V]) PopMin() *SortedSetNode[K, SCORE, V] {
 // get the element with maximum score, nil if the set is empty
 // Time Complexity : O(1)
 func (this *SortedSet[K, SCORE, V]) PeekMax() *SortedSetNode[K, SCORE, V] {
-	return this.tail
+	return this.lastAlive(this.tail, this.expiredNow())
 }
 
 // get and remove the element with maximum score, nil if the set is empty
 // Time complexity of this method is : O(log(N))
 func (this *SortedSet[K, SCORE, V]) PopMax() *SortedSetNode[K, SCORE, V] {
-	x := this.tail
+	x := this.PeekMax()
 	if x != nil {
 		this.Remove(x.key)
 	}
@@ -229,36 +505,62 @@ func (this *SortedSet[K, SCORE, V]) PopMax() *SortedSetNode[K, SCORE, V] {
 }
 
 // Add an element into the sorted set with specific key / value / score.
-// if the element is added, this method returns true; otherwise false means updated
+// if the element is added, this method returns true; otherwise false means
+// updated, or rejected by the capacity of the set (see SetCapacity)
 //
 // Time complexity of this method is : O(log(N))
 func (this *SortedSet[K, SCORE, V]) AddOrUpdate(key K, score SCORE, value V) bool {
-	var newNode *SortedSetNode[K, SCORE, V] = nil
-
-	found := this.lookup(key)
+	found := this.access(key)
 	if found != nil {
-		// score does not change, only update value
-		if found.score == score {
-			found.Value = value
-		} else { // score changes, delete and re-insert
-			this.delete(found.score, found.key)
-			newNode = this.insertNode(score, key, value)
+		this.setValue(found, value)
+		// score changes, move the node
+		if this.scoreCmp(found.score, score) != 0 {
+			this.updateScore(found, score)
 		}
-	} else {
-		newNode = this.insertNode(score, key, value)
+		return false
 	}
 
-	if newNode != nil {
-		this.dict.Store(key, newNode)
+	return this.insert(score, key, value) != nil
+}
+
+// setValue sets the value of a node in the set
+func (this *SortedSet[K, SCORE, V]) setValue(node *SortedSetNode[K, SCORE, V], value V) {
+	if this.cloning(node.key) {
+		defer this.unlockClones()
 	}
-	return found == nil
+	node.Value = value
+}
+
+// updateScore changes the score of a node in the set.
+// If the node stays between its neighbours, the score is updated in place in
+// O(1); otherwise the node is deleted and re-inserted.
+func (this *SortedSet[K, SCORE, V]) updateScore(node *SortedSetNode[K, SCORE, V], score SCORE) *SortedSetNode[K, SCORE, V] {
+	prev, next := node.backward, node.level[0].forward
+	if (prev == nil || this.compare(prev, score, node.key) < 0) &&
+		(next == nil || this.compare(next, score, node.key) > 0) {
+		if this.cloning(node.key) {
+			defer this.unlockClones()
+		}
+		node.score = score
+		this.version++
+		return node
+	}
+
+	deadline := node.deadline
+	this.delete(node.score, node.key)
+	x := this.insertNode(score, node.key, node.Value)
+	this.dict.Store(x.key, x)
+	if deadline != 0 {
+		this.setDeadline(x, deadline)
+	}
+	return x
 }
 
 // Delete element specified by key
 //
 // Time complexity of this method is : O(log(N))
 func (this *SortedSet[K, SCORE, V]) Remove(key K) *SortedSetNode[K, SCORE, V] {
-	found := this.lookup(key)
+	found := this.access(key)
 	if found != nil {
 		this.delete(found.score, found.key)
 		return found
@@ -268,10 +570,16 @@ func (this *SortedSet[K, SCORE, V]) Remove(key K) *SortedSetNode[K, SCORE, V] {
 
 // Has reports whether key is a member of the set. It is the only method safe
 // to call from a goroutine other than the one owning the set (it reads the
-// sync.Map only, never skiplist structure or node fields).
+// sync.Map and the atomic deadline of the node only, never skiplist structure
+// or other node fields, and calls Options.Clock to check the deadline). An
+// expired member is reported absent.
 func (this *SortedSet[K, SCORE, V]) Has(key K) bool {
-	_, ok := this.dict.Load(key)
-	return ok
+	v, ok := this.dict.Load(key)
+	if !ok {
+		return false
+	}
+	deadline := atomic.LoadInt64(&v.(*SortedSetNode[K, SCORE, V]).deadline)
+	return deadline == 0 || deadline > this.now()
 }
 
 // lookup returns the node for key, or nil.
@@ -286,6 +594,7 @@ type GetRangeByScoreOptions struct {
 	Limit        int  // limit the max nodes to return
 	ExcludeStart bool // exclude start value, so it search in interval (start, end] or (start, end)
 	ExcludeEnd   bool // exclude end value, so it search in interval [start, end) or (start, end)
+	Reverse      bool // return the nodes from the maximum score to the minimum score, even if start is not after end
 }
 
 // Get the nodes whose score within the specific range
@@ -294,114 +603,232 @@ type GetRangeByScoreOptions struct {
 //
 // Time complexity of this method is : O(log(N))
 func (this *SortedSet[K, SCORE, V]) GetRangeByScore(start SCORE, end SCORE, options *GetRangeByScoreOptions) []*SortedSetNode[K, SCORE, V] {
+	return this.GetRangeByScoreBound(ScoreInclusive(start), ScoreInclusive(end), options)
+}
+
+// Get the nodes whose score within the range between the bounds start and end
+//
+// If start is after end, or Reverse of options is set, the returned array is in reserved order.
+// ExcludeStart and ExcludeEnd of options turn an inclusive bound into an exclusive one.
+// If options is nil, it searchs without any limit by default
+//
+// Time complexity of this method is : O(log(N)) to locate start, then O(M) for M returned nodes
+func (this *SortedSet[K, SCORE, V]) GetRangeByScoreBound(start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions) []*SortedSetNode[K, SCORE, V] {
+	var nodes []*SortedSetNode[K, SCORE, V]
+	this.scanByScore(start, end, options, func(x *SortedSetNode[K, SCORE, V]) bool {
+		nodes = append(nodes, x)
+		return true
+	})
+	return nodes
+}
+
+// scanByScore applies fn to the nodes whose score within the specific range,
+// in the order GetRangeByScoreBound returns them, until fn returns false.
+// The expired nodes are skipped.
+func (this *SortedSet[K, SCORE, V]) scanByScore(start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions, fn func(x *SortedSetNode[K, SCORE, V]) bool) {
+	now := this.expiredNow()
 	var limit int = int((^uint(0)) >> 1)
 	if options != nil && options.Limit > 0 {
 		limit = options.Limit
 	}
 
-	excludeStart := options != nil && options.ExcludeStart
-	excludeEnd := options != nil && options.ExcludeEnd
-	reverse := start > end
+	start, end = applyExcludes(start, end, options)
+	reverse := start.after(this.scoreCmp, end)
 	if reverse {
 		start, end = end, start
-		excludeStart, excludeEnd = excludeEnd, excludeStart
 	}
-
-	var nodes []*SortedSetNode[K, SCORE, V]
+	if options != nil && options.Reverse {
+		reverse = true
+	}
 
 	if this.length == 0 {
-		return nodes
+		return
 	}
 
+	x := this.header
 	if reverse { // search from end to start
-		x := this.header
-
-		if excludeEnd {
-			for i := this.level - 1; i >= 0; i-- {
-				for x.level[i].forward != nil &&
-					x.level[i].forward.score < end {
-					x = x.level[i].forward
-				}
-			}
-		} else {
-			for i := this.level - 1; i >= 0; i-- {
-				for x.level[i].forward != nil &&
-					x.level[i].forward.score <= end {
-					x = x.level[i].forward
-				}
+		for i := this.level - 1; i >= 0; i-- {
+			for x.level[i].forward != nil &&
+				end.lteMax(this.scoreCmp, x.level[i].forward.score) {
+				x = x.level[i].forward
 			}
 		}
 
-		for x != nil && limit > 0 {
-			if excludeStart {
-				if x.score <= start {
-					break
-				}
-			} else {
-				if x.score < start {
-					break
-				}
-			}
-
+		/* Current node is the last in the range, or the header */
+		for x != nil && x != this.header && limit > 0 && start.gteMin(this.scoreCmp, x.score) {
 			next := x.backward
 
-			nodes = append(nodes, x)
-			limit--
+			if x.aliveAt(now) {
+				if !fn(x) {
+					return
+				}
+				limit--
+			}
 
 			x = next
 		}
-	} else {
-		// search from start to end
-		x := this.header
-		if excludeStart {
-			for i := this.level - 1; i >= 0; i-- {
-				for x.level[i].forward != nil &&
-					x.level[i].forward.score <= start {
-					x = x.level[i].forward
-				}
-			}
-		} else {
-			for i := this.level - 1; i >= 0; i-- {
-				for x.level[i].forward != nil &&
-					x.level[i].forward.score < start {
-					x = x.level[i].forward
-				}
+	} else { // search from start to end
+		for i := this.level - 1; i >= 0; i-- {
+			for x.level[i].forward != nil &&
+				!start.gteMin(this.scoreCmp, x.level[i].forward.score) {
+				x = x.level[i].forward
 			}
 		}
 
-		/* Current node is the last with score < or <= start. */
+		/* Current node is the last before the range */
 		x = x.level[0].forward
 
-		for x != nil && limit > 0 {
-			if excludeEnd {
-				if x.score >= end {
-					break
-				}
-			} else {
-				if x.score > end {
-					break
+		for x != nil && limit > 0 && end.lteMax(this.scoreCmp, x.score) {
+			next := x.level[0].forward
+
+			if x.aliveAt(now) {
+				if !fn(x) {
+					return
 				}
+				limit--
 			}
 
-			next := x.level[0].forward
+			x = next
+		}
+	}
+}
 
-			nodes = append(nodes, x)
-			limit--
+// Remove the nodes whose score within the specific range and return the number of removed nodes
+//
+// The range is unlinked in a single pass from the first node in the range.
+// If options is nil, it removes nodes in interval [start, end] without any limit by default.
+// If start is greater than end, the bounds are swapped, and Limit still counts
+// from the lowest score. Reverse is ignored.
+// If fn is not nil, it is called with every removed node.
+// The expired nodes met in the range are removed too, but neither counted nor
+// passed to fn.
+//
+// Time complexity of this method is : O(log(N)+M) for M removed nodes
+func (this *SortedSet[K, SCORE, V]) RemoveRangeByScore(start SCORE, end SCORE, options *GetRangeByScoreOptions, fn func(node *SortedSetNode[K, SCORE, V])) int {
+	return this.RemoveRangeByScoreBound(ScoreInclusive(start), ScoreInclusive(end), options, fn)
+}
 
-			x = next
+// Remove the nodes whose score within the range between the bounds start and
+// end, and return the number of removed nodes
+//
+// See RemoveRangeByScore for the meaning of options and fn.
+//
+// Time complexity of this method is : O(log(N)+M) for M removed nodes
+func (this *SortedSet[K, SCORE, V]) RemoveRangeByScoreBound(start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions, fn func(node *SortedSetNode[K, SCORE, V])) int {
+	now := this.expiredNow()
+	var update [SKIPLIST_MAXLEVEL]*SortedSetNode[K, SCORE, V]
+
+	var limit int = int((^uint(0)) >> 1)
+	if options != nil && options.Limit > 0 {
+		limit = options.Limit
+	}
+
+	start, end = applyExcludes(start, end, options)
+	if start.after(this.scoreCmp, end) {
+		start, end = end, start
+	}
+
+	x := this.header
+	for i := this.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			!start.gteMin(this.scoreCmp, x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	/* Current node is the last before the range */
+	removed := 0
+	x = x.level[0].forward
+	for x != nil && removed < limit && end.lteMax(this.scoreCmp, x.score) {
+		next := x.level[0].forward
+		this.deleteNode(x, update)
+		if x.aliveAt(now) {
+			if fn != nil {
+				fn(x)
+			}
+			removed++
 		}
+		x = next
 	}
+	return removed
+}
 
-	return nodes
+// countWhile returns the number of leading nodes satisfying fn, which must
+// hold for a prefix of the set. The spans are summed, so no node is visited
+// more than once per level.
+func (this *SortedSet[K, SCORE, V]) countWhile(fn func(x *SortedSetNode[K, SCORE, V]) bool) int {
+	var count int64
+	x := this.header
+	for i := this.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && fn(x.level[i].forward) {
+			count += x.level[i].span
+			x = x.level[i].forward
+		}
+	}
+	return int(count)
+}
+
+// Get the number of nodes whose score within the specific range, like ZCOUNT
+//
+// If options is nil, it counts nodes in interval [start, end] without any limit by default.
+// If start is greater than end, the bounds are swapped.
+// A positive Limit caps the returned count
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet[K, SCORE, V]) CountByScore(start SCORE, end SCORE, options *GetRangeByScoreOptions) int {
+	return this.CountByScoreBound(ScoreInclusive(start), ScoreInclusive(end), options)
+}
+
+// Get the number of nodes whose score within the range between the bounds start and end
+//
+// See CountByScore for the meaning of options.
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet[K, SCORE, V]) CountByScoreBound(start ScoreBound[SCORE], end ScoreBound[SCORE], options *GetRangeByScoreOptions) int {
+	now := this.expiredNow()
+	start, end = applyExcludes(start, end, options)
+	if start.after(this.scoreCmp, end) {
+		start, end = end, start
+	}
+
+	count := this.countWhile(func(x *SortedSetNode[K, SCORE, V]) bool {
+		return end.lteMax(this.scoreCmp, x.score)
+	}) - this.countWhile(func(x *SortedSetNode[K, SCORE, V]) bool {
+		return !start.gteMin(this.scoreCmp, x.score)
+	}) - this.countExpired(now, func(x *SortedSetNode[K, SCORE, V]) bool {
+		return start.gteMin(this.scoreCmp, x.score) && end.lteMax(this.scoreCmp, x.score)
+	})
+	if count < 0 {
+		count = 0
+	}
+	if options != nil && options.Limit > 0 && count > options.Limit {
+		count = options.Limit
+	}
+	return count
+}
+
+// Get the number of nodes whose score is less than score
+//
+// This is also the rank a node with this score would have minus one, if it
+// were ordered before all nodes of equal score.
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet[K, SCORE, V]) RankOfScore(score SCORE) int {
+	less := func(x *SortedSetNode[K, SCORE, V]) bool {
+		return this.scoreCmp(x.score, score) < 0
+	}
+	return this.countWhile(less) - this.countExpired(this.expiredNow(), less)
 }
 
-// sanitizeIndexes return start, end, and reverse flag
-func (this *SortedSet[K, SCORE, V]) sanitizeIndexes(start int, end int) (int, int, bool) {
+// sanitizeIndexes return start, end, and reverse flag, for a set of length
+// nodes not expired
+func (this *SortedSet[K, SCORE, V]) sanitizeIndexes(start int, end int, length int) (int, int, bool) {
 	if start < 0 {
-		start = int(this.length) + start + 1
+		start = length + start + 1
 	}
 	if end < 0 {
-		end = int(this.length) + end + 1
+		end = length + end + 1
 	}
 	if start <= 0 {
 		start = 1
@@ -436,6 +863,28 @@ func (this *SortedSet[K, SCORE, V]) findNodeByRank(start int, remove bool) (trav
 	return
 }
 
+// findLiveByRank returns the node at rank, counting only the nodes not
+// expired at now, as returned by expiredNow, or nil
+//
+// Time complexity of this method is : O(log(N)+D) for D expired nodes
+func (this *SortedSet[K, SCORE, V]) findLiveByRank(rank int, now int64) *SortedSetNode[K, SCORE, V] {
+	_, x, _ := this.findNodeByRank(rank, false)
+	x = x.level[0].forward
+	if now == 0 || x == nil {
+		return x
+	}
+	// x is at rank among all the nodes, so the node wanted is as many live
+	// nodes after the first live one from x as expired nodes precede x
+	skip := this.countExpired(now, func(e *SortedSetNode[K, SCORE, V]) bool {
+		return this.compare(e, x.score, x.key) < 0
+	})
+	x = this.firstAlive(x, now)
+	for ; x != nil && skip > 0; skip-- {
+		x = this.firstAlive(x.level[0].forward, now)
+	}
+	return x
+}
+
 // Get nodes within specific rank range [start, end]
 // Note that the rank is 1-based integer. Rank 1 means the first node; Rank -1 means the last node;
 //
@@ -444,10 +893,29 @@ func (this *SortedSet[K, SCORE, V]) findNodeByRank(start int, remove bool) (trav
 //
 // Time complexity of this method is : O(log(N))
 func (this *SortedSet[K, SCORE, V]) GetRangeByRank(start int, end int, remove bool) []*SortedSetNode[K, SCORE, V] {
-	start, end, reverse := this.sanitizeIndexes(start, end)
+	now := this.expiredNow()
+	start, end, reverse := this.sanitizeIndexes(start, end, this.liveLength(now))
 
 	var nodes []*SortedSetNode[K, SCORE, V]
 
+	if now != 0 {
+		// the ranks skip the expired nodes, so the nodes are found first and
+		// removed by key
+		for x := this.findLiveByRank(start, now); x != nil && start <= end; x = this.firstAlive(x.level[0].forward, now) {
+			nodes = append(nodes, x)
+			start++
+		}
+		if remove {
+			for _, x := range nodes {
+				this.delete(x.score, x.key)
+			}
+		}
+		if reverse {
+			slices.Reverse(nodes)
+		}
+		return nodes
+	}
+
 	traversed, x, update := this.findNodeByRank(start, remove)
 
 	traversed++
@@ -493,7 +961,7 @@ func (this *SortedSet[K, SCORE, V]) GetByRank(rank int, remove bool) *SortedSetN
 // If node is not found, nil is returned
 // Time complexity : O(1)
 func (this *SortedSet[K, SCORE, V]) GetByKey(key K) *SortedSetNode[K, SCORE, V] {
-	return this.lookup(key)
+	return this.get(key)
 }
 
 // Find the rank of the node specified by key
@@ -503,22 +971,34 @@ func (this *SortedSet[K, SCORE, V]) GetByKey(key K) *SortedSetNode[K, SCORE, V]
 //
 // Time complexity of this method is : O(log(N))
 func (this *SortedSet[K, SCORE, V]) FindRank(key K) int {
-	var rank int = 0
-	node := this.lookup(key)
+	node := this.get(key)
 	if node != nil {
-		x := this.header
-		for i := this.level - 1; i >= 0; i-- {
-			for x.level[i].forward != nil &&
-				(x.level[i].forward.score < node.score ||
-					(x.level[i].forward.score == node.score &&
-						x.level[i].forward.key <= node.key)) {
-				rank += int(x.level[i].span)
-				x = x.level[i].forward
-			}
+		return this.liveRankOf(node)
+	}
+	return 0
+}
 
-			if x.key == key {
-				return rank
-			}
+// liveRankOf returns the rank of a node linked in the skip list, counting
+// only the nodes not expired
+func (this *SortedSet[K, SCORE, V]) liveRankOf(node *SortedSetNode[K, SCORE, V]) int {
+	return this.rankOf(node) - this.countExpired(this.expiredNow(), func(x *SortedSetNode[K, SCORE, V]) bool {
+		return this.compare(x, node.score, node.key) < 0
+	})
+}
+
+// rankOf returns the rank of a node linked in the skip list
+func (this *SortedSet[K, SCORE, V]) rankOf(node *SortedSetNode[K, SCORE, V]) int {
+	var rank int = 0
+	x := this.header
+	for i := this.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			this.compare(x.level[i].forward, node.score, node.key) <= 0 {
+			rank += int(x.level[i].span)
+			x = x.level[i].forward
+		}
+
+		if x == node {
+			return rank
 		}
 	}
 	return 0
@@ -534,30 +1014,41 @@ func (this *SortedSet[K, SCORE, V]) IterFuncRangeByRank(start int, end int, fn f
 	if fn == nil {
 		return
 	}
+	this.scanByRank(start, end, func(x *SortedSetNode[K, SCORE, V]) bool {
+		return fn(x.key, x.Value)
+	})
+}
 
-	start, end, reverse := this.sanitizeIndexes(start, end)
-	traversed, x, _ := this.findNodeByRank(start, false)
-	var nodes []*SortedSetNode[K, SCORE, V]
-
-	x = x.level[0].forward
-	for x != nil && traversed < end {
-		next := x.level[0].forward
-
-		if reverse {
-			nodes = append(nodes, x)
-		} else if !fn(x.key, x.Value) {
+// scanByRank applies fn to the nodes within specific rank range [start, end],
+// in reverse order if start is greater than end, until fn returns false.
+// The expired nodes are skipped and not ranked.
+func (this *SortedSet[K, SCORE, V]) scanByRank(start int, end int, fn func(x *SortedSetNode[K, SCORE, V]) bool) {
+	now := this.expiredNow()
+	length := this.liveLength(now)
+	start, end, reverse := this.sanitizeIndexes(start, end, length)
+	if reverse {
+		end = min(end, length)
+		if start > end {
 			return
 		}
-
-		traversed++
-		x = next
-	}
-
-	if reverse {
-		for i := len(nodes) - 1; i >= 0; i-- {
-			if !fn(nodes[i].key, nodes[i].Value) {
+		// walk the backward pointers from the node at rank end
+		x := this.findLiveByRank(end, now)
+		for traversed := end; x != nil && traversed >= start; traversed-- {
+			next := this.lastAlive(x.backward, now)
+			if !fn(x) {
 				return
 			}
+			x = next
 		}
+		return
+	}
+
+	x := this.findLiveByRank(start, now)
+	for traversed := start; x != nil && traversed <= end; traversed++ {
+		next := this.firstAlive(x.level[0].forward, now)
+		if !fn(x) {
+			return
+		}
+		x = next
 	}
 }
@@ -1,8 +1,14 @@
 package sortedset
 
 import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func checkOrder(t *testing.T, nodes []*SortedSetNode[string, int64, string], expectedOrder []string) {
@@ -172,6 +178,17 @@ func TestCase2(t *testing.T) {
 	})
 	checkOrder(t, nodes, []string{"c", "g"})
 
+	nodes = sortedset.GetRangeByScore(50, 100, &GetRangeByScoreOptions{
+		Limit:   2,
+		Reverse: true,
+	})
+	checkOrder(t, nodes, []string{"c", "g"})
+
+	nodes = sortedset.GetRangeByScore(99, 99, &GetRangeByScoreOptions{
+		Reverse: true,
+	})
+	checkOrder(t, nodes, []string{"g", "f", "e"})
+
 	minNode := sortedset.PeekMin()
 	if minNode == nil || minNode.Key() != "d" {
 		t.Error("PeekMin() does not return expected value `d`")
@@ -272,3 +289,282 @@ func TestConcurrentReaders(t *testing.T) {
 	close(stop)
 	wg.Wait()
 }
+
+func TestRemoveRangeByScore(t *testing.T) {
+	sortedset := New[string, int64, string]()
+
+	sortedset.AddOrUpdate("a", 89, "Kelly")
+	sortedset.AddOrUpdate("c", 100, "Jordon")
+	sortedset.AddOrUpdate("d", -321, "Park")
+	sortedset.AddOrUpdate("e", 99, "ntrnrt")
+	sortedset.AddOrUpdate("f", 99, "Lyman")
+	sortedset.AddOrUpdate("g", 99, "Singleton")
+	sortedset.AddOrUpdate("h", 70, "Audrey")
+
+	var keys []string
+	collect := func(node *SortedSetNode[string, int64, string]) {
+		keys = append(keys, node.Key())
+	}
+
+	removed := sortedset.RemoveRangeByScore(89, 100, &GetRangeByScoreOptions{
+		ExcludeStart: true,
+		ExcludeEnd:   true,
+	}, collect)
+	if removed != 3 {
+		t.Errorf("RemoveRangeByScore() removed %d nodes, expected 3", removed)
+	}
+	if len(keys) != 3 || keys[0] != "e" || keys[1] != "f" || keys[2] != "g" {
+		t.Errorf("RemoveRangeByScore() called fn with %v", keys)
+	}
+	checkOrder(t, sortedset.GetRangeByRank(1, -1, false), []string{"d", "h", "a", "c"})
+
+	// reversed bounds and limit
+	if removed := sortedset.RemoveRangeByScore(1000, -1000, &GetRangeByScoreOptions{Limit: 2}, nil); removed != 2 {
+		t.Errorf("RemoveRangeByScore() removed %d nodes, expected 2", removed)
+	}
+	checkOrder(t, sortedset.GetRangeByRank(1, -1, false), []string{"a", "c"})
+
+	if removed := sortedset.RemoveRangeByScore(100, 200, nil, nil); removed != 1 {
+		t.Errorf("RemoveRangeByScore() removed %d nodes, expected 1", removed)
+	}
+	if sortedset.PeekMax().Key() != "a" || sortedset.FindRank("a") != 1 || sortedset.Has("c") {
+		t.Error("RemoveRangeByScore() left the set inconsistent")
+	}
+	if removed := sortedset.RemoveRangeByScore(0, 10, nil, nil); removed != 0 {
+		t.Errorf("RemoveRangeByScore() removed %d nodes, expected 0", removed)
+	}
+}
+
+func TestCountByScore(t *testing.T) {
+	sortedset := New[string, int64, string]()
+
+	sortedset.AddOrUpdate("a", 89, "Kelly")
+	sortedset.AddOrUpdate("c", 100, "Jordon")
+	sortedset.AddOrUpdate("d", -321, "Park")
+	sortedset.AddOrUpdate("e", 99, "ntrnrt")
+	sortedset.AddOrUpdate("f", 99, "Lyman")
+	sortedset.AddOrUpdate("g", 99, "Singleton")
+	sortedset.AddOrUpdate("h", 70, "Audrey")
+
+	for _, options := range []*GetRangeByScoreOptions{
+		nil,
+		{ExcludeStart: true},
+		{ExcludeEnd: true},
+		{ExcludeStart: true, ExcludeEnd: true},
+		{Limit: 2},
+	} {
+		for _, bounds := range [][2]int64{{-500, 500}, {99, 100}, {100, 99}, {70, 89}, {99, 99}, {101, 200}, {-1000, -500}} {
+			expected := len(sortedset.GetRangeByScore(bounds[0], bounds[1], options))
+			if count := sortedset.CountByScore(bounds[0], bounds[1], options); count != expected {
+				t.Errorf("CountByScore(%d, %d, %+v) returned %d, expected %d", bounds[0], bounds[1], options, count, expected)
+			}
+		}
+	}
+
+	for score, expected := range map[int64]int{-1000: 0, -321: 0, -320: 1, 89: 2, 99: 3, 100: 6, 1000: 7} {
+		if rank := sortedset.RankOfScore(score); rank != expected {
+			t.Errorf("RankOfScore(%d) returned %d, expected %d", score, rank, expected)
+		}
+	}
+}
+
+type teamMember struct {
+	team string
+	name string
+}
+
+func TestNewFunc(t *testing.T) {
+	// latest deadline first, keys ordered by team then name
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sortedset := NewFunc[teamMember, time.Time, int](
+		func(a, b time.Time) int { return b.Compare(a) },
+		func(a, b teamMember) int {
+			if c := strings.Compare(a.team, b.team); c != 0 {
+				return c
+			}
+			return strings.Compare(a.name, b.name)
+		},
+	)
+
+	sortedset.AddOrUpdate(teamMember{"red", "a"}, base.Add(1*time.Hour), 1)
+	sortedset.AddOrUpdate(teamMember{"blue", "b"}, base.Add(3*time.Hour), 2)
+	sortedset.AddOrUpdate(teamMember{"red", "c"}, base.Add(3*time.Hour), 3)
+	sortedset.AddOrUpdate(teamMember{"blue", "d"}, base, 4)
+	sortedset.AddOrUpdate(teamMember{"red", "a"}, base.Add(2*time.Hour), 5)
+
+	checkNames := func(nodes []*SortedSetNode[teamMember, time.Time, int], expected string) {
+		t.Helper()
+		var names []string
+		for _, node := range nodes {
+			names = append(names, node.Key().name)
+		}
+		if got := strings.Join(names, ""); got != expected {
+			t.Errorf("nodes are %q, expected %q", got, expected)
+		}
+	}
+	checkNames(sortedset.GetRangeByRank(1, -1, false), "bcad")
+	checkNames(sortedset.GetRangeByScore(base.Add(2*time.Hour), base, nil), "ad")
+	checkNames(sortedset.GetRangeByScoreBound(ScoreNegInf[time.Time](), ScoreExclusive(base.Add(2*time.Hour)), nil), "bc")
+
+	if rank := sortedset.FindRank(teamMember{"red", "a"}); rank != 3 {
+		t.Errorf("FindRank() returned %d, expected 3", rank)
+	}
+	if node := sortedset.Remove(teamMember{"red", "c"}); node == nil || node.Value != 3 {
+		t.Error("Remove() does not return expected value 3")
+	}
+	if count := sortedset.CountByScore(base.Add(time.Hour), base.Add(3*time.Hour), nil); count != 2 {
+		t.Errorf("CountByScore() returned %d, expected 2", count)
+	}
+	checkNames(sortedset.Clone().GetRangeByRank(1, -1), "bad")
+}
+
+func TestZeroSortedSetDefaultCompare(t *testing.T) {
+	// a zero set of predeclared ordered types gets the order of New once decoded
+	var sortedset SortedSet[string, float32, string]
+	if err := json.Unmarshal([]byte(`[{"key":"b","score":2,"value":""},{"key":"a","score":3,"value":""},{"key":"c","score":1,"value":""}]`), &sortedset); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for key := range sortedset.All() {
+		keys = append(keys, key)
+	}
+	if strings.Join(keys, "") != "cba" {
+		t.Errorf("keys are %v, expected [c b a]", keys)
+	}
+	if defaultCompare[teamMember]() != nil || defaultCompare[time.Duration]() != nil {
+		t.Error("defaultCompare() returned a function for a type that is not predeclared")
+	}
+
+	// other types need New or NewFunc
+	defer func() {
+		if recover() == nil {
+			t.Error("decoding a zero set of named scores did not panic")
+		}
+	}()
+	var named SortedSet[string, time.Duration, string]
+	json.Unmarshal([]byte(`[]`), &named)
+}
+
+func levelsOf(sortedset *SortedSet[int, int, int]) []int {
+	var levels []int
+	for x := sortedset.header.level[0].forward; x != nil; x = x.level[0].forward {
+		levels = append(levels, len(x.level))
+	}
+	return levels
+}
+
+func TestNewWithOptions(t *testing.T) {
+	// the same seed builds the same skip list
+	a := NewWithOptions[int, int, int](&Options{Rand: rand.NewSource(42)})
+	b := NewWithOptions[int, int, int](&Options{Rand: rand.NewSource(42)})
+	for i := 0; i < 1000; i++ {
+		a.AddOrUpdate(i, i%37, i)
+		b.AddOrUpdate(i, i%37, i)
+	}
+	if !slices.Equal(levelsOf(a), levelsOf(b)) {
+		t.Error("sets with the same seed have different levels")
+	}
+
+	sortedset := NewWithOptions[int, int, int](&Options{P: 0.5, MaxLevel: 3, Rand: rand.NewSource(1)})
+	for i := 0; i < 1000; i++ {
+		sortedset.AddOrUpdate(i, -i, i)
+	}
+	if highest := slices.Max(levelsOf(sortedset)); highest != 3 {
+		t.Errorf("highest level is %d, expected 3", highest)
+	}
+	if node := sortedset.GetByRank(10, false); node == nil || node.Key() != 990 {
+		t.Error("GetByRank() does not return expected value 990")
+	}
+	if rank := sortedset.FindRank(500); rank != 500 {
+		t.Errorf("FindRank() returned %d, expected 500", rank)
+	}
+
+	for _, test := range []struct {
+		options  Options
+		maxLevel int
+	}{
+		{Options{}, SKIPLIST_MAXLEVEL},
+		{Options{CapacityHint: 1 << 20}, 11},
+		{Options{P: 0.5, CapacityHint: 1000}, 11},
+		{Options{MaxLevel: 100, CapacityHint: 1000}, SKIPLIST_MAXLEVEL},
+		{Options{MaxLevel: 4, CapacityHint: 1 << 20}, 4},
+	} {
+		if generator := newLevelGenerator(&test.options); generator.maxLevel != test.maxLevel {
+			t.Errorf("%+v gives max level %d, expected %d", test.options, generator.maxLevel, test.maxLevel)
+		}
+	}
+}
+
+// benchmarks of the P tradeoff: a lower P allocates fewer levels per node,
+// a higher P shortens the searches
+var benchmarkP = []float64{0.125, 0.25, 0.5}
+
+func BenchmarkAddOrUpdateP(b *testing.B) {
+	for _, p := range benchmarkP {
+		b.Run(fmt.Sprintf("P=%v", p), func(b *testing.B) {
+			sortedset := NewWithOptions[int, int, int](&Options{P: p, Rand: rand.NewSource(1)})
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				sortedset.AddOrUpdate(i, (i*7919)%1000003, i)
+			}
+			levels := 0
+			for _, level := range levelsOf(sortedset) {
+				levels += level
+			}
+			b.ReportMetric(float64(levels)/float64(sortedset.GetCount()), "levels/node")
+		})
+	}
+}
+
+func BenchmarkGetRangeByScoreP(b *testing.B) {
+	for _, p := range benchmarkP {
+		b.Run(fmt.Sprintf("P=%v", p), func(b *testing.B) {
+			sortedset := NewWithOptions[int, int, int](&Options{P: p, Rand: rand.NewSource(1)})
+			for i := 0; i < 100000; i++ {
+				sortedset.AddOrUpdate(i, (i*7919)%1000003, i)
+			}
+			options := &GetRangeByScoreOptions{Limit: 10}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				sortedset.GetRangeByScore((i*104729)%1000003, 1000003, options)
+			}
+		})
+	}
+}
+
+func BenchmarkFindRankP(b *testing.B) {
+	for _, p := range benchmarkP {
+		b.Run(fmt.Sprintf("P=%v", p), func(b *testing.B) {
+			sortedset := NewWithOptions[int, int, int](&Options{P: p, Rand: rand.NewSource(1)})
+			for i := 0; i < 100000; i++ {
+				sortedset.AddOrUpdate(i, (i*7919)%1000003, i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				sortedset.FindRank((i * 31) % 100000)
+			}
+		})
+	}
+}
+
+// benchmarks of a set created by New; the same functions compiled against the
+// first release, whose comparisons were inlined, give the cost of scoreCmp
+// and keyCmp
+func BenchmarkAddOrUpdate(b *testing.B) {
+	sortedset := New[int, int, int]()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sortedset.AddOrUpdate(i, (i*7919)%1000003, i)
+	}
+}
+
+func BenchmarkGetByRank(b *testing.B) {
+	sortedset := New[int, int, int]()
+	for i := 0; i < 100000; i++ {
+		sortedset.AddOrUpdate(i, (i*7919)%1000003, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sortedset.GetByRank((i*31)%100000+1, false)
+	}
+}
@@ -24,20 +24,19 @@
 
 package sortedset
 
-import "golang.org/x/exp/constraints"
-
-type SortedSetLevel[K constraints.Ordered, SCORE constraints.Ordered, V any] struct {
+type SortedSetLevel[K comparable, SCORE any, V any] struct {
 	forward *SortedSetNode[K, SCORE, V]
 	span    int64
 }
 
 // Node in skip list
-type SortedSetNode[K constraints.Ordered, SCORE constraints.Ordered, V any] struct {
+type SortedSetNode[K comparable, SCORE any, V any] struct {
 	key      K     // unique key of this node
 	Value    V     // associated data
 	score    SCORE // score to determine the order of this node in the set
 	backward *SortedSetNode[K, SCORE, V]
 	level    []SortedSetLevel[K, SCORE, V]
+	deadline int64 // Unix nanoseconds when the node expires, 0 for never; atomic for Has
 }
 
 // Get the key of the node
@@ -49,3 +48,15 @@ func (this *SortedSetNode[K, SCORE, V]) Key() K {
 func (this *SortedSetNode[K, SCORE, V]) Score() SCORE {
 	return this.score
 }
+
+// clone returns a copy of the node detached from the skip list, nil for nil
+func (this *SortedSetNode[K, SCORE, V]) clone() *SortedSetNode[K, SCORE, V] {
+	if this == nil {
+		return nil
+	}
+	return &SortedSetNode[K, SCORE, V]{
+		key:   this.key,
+		Value: this.Value,
+		score: this.score,
+	}
+}
@@ -0,0 +1,143 @@
+package sortedsettest
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/wangjia184/sortedset"
+)
+
+const (
+	opAddOrUpdate = iota
+	opRemove
+	opPopMin
+	opPopMax
+	opGetRangeByScore
+	opGetRangeByRank
+	opGetByRank
+	opFindRank
+	opCount
+)
+
+// decoder reads operation arguments from the fuzz input, zeros past the end
+type decoder struct {
+	data []byte
+}
+
+func (this *decoder) next() int {
+	if len(this.data) == 0 {
+		return 0
+	}
+	b := this.data[0]
+	this.data = this.data[1:]
+	return int(b)
+}
+
+// key returns one of few keys, so keys are often updated and removed
+func (this *decoder) key() int { return this.next() % 32 }
+
+// score returns one of few scores, so scores are often tied
+func (this *decoder) score() int { return this.next()%16 - 8 }
+
+// rank returns a rank around the valid ranks, negative ones included
+func (this *decoder) rank() int { return this.next()%48 - 24 }
+
+// runOperations applies the operations encoded in data to a set and a model
+// and fails at the first difference
+func runOperations(t *testing.T, data []byte) {
+	set := sortedset.New[int, int, int]()
+	model := NewModel[int, int, int]()
+	input := &decoder{data: data}
+
+	for step := 0; len(input.data) > 0; step++ {
+		op := input.next() % opCount
+		var err error
+		switch op {
+		case opAddOrUpdate:
+			key, score := input.key(), input.score()
+			if got, expected := set.AddOrUpdate(key, score, step), model.AddOrUpdate(key, score, step); got != expected {
+				t.Fatalf("step %d: AddOrUpdate(%d, %d) returned %v, the model returned %v", step, key, score, got, expected)
+			}
+		case opRemove:
+			key := input.key()
+			err = compareOne(set.Remove(key), model.Remove(key))
+		case opPopMin:
+			err = compareOne(set.PopMin(), model.PopMin())
+		case opPopMax:
+			err = compareOne(set.PopMax(), model.PopMax())
+		case opGetRangeByScore:
+			start, end, flags := input.score(), input.score(), input.next()
+			var options *sortedset.GetRangeByScoreOptions
+			if flags&1 != 0 {
+				options = &sortedset.GetRangeByScoreOptions{
+					Limit:        flags >> 3 % 4,
+					ExcludeStart: flags&2 != 0,
+					ExcludeEnd:   flags&4 != 0,
+				}
+			}
+			err = Compare(set.GetRangeByScore(start, end, options), model.GetRangeByScore(start, end, options))
+		case opGetRangeByRank:
+			start, end, remove := input.rank(), input.rank(), input.next()%2 == 1
+			err = Compare(set.GetRangeByRank(start, end, remove), model.GetRangeByRank(start, end, remove))
+		case opGetByRank:
+			rank, remove := input.rank(), input.next()%2 == 1
+			var expected *Entry[int, int, int]
+			if entries := model.GetRangeByRank(rank, rank, remove); len(entries) == 1 {
+				expected = &entries[0]
+			}
+			err = compareOne(set.GetByRank(rank, remove), expected)
+		case opFindRank:
+			key := input.key()
+			if got, expected := set.FindRank(key), model.FindRank(key); got != expected {
+				t.Fatalf("step %d: FindRank(%d) returned %d, the model returned %d", step, key, got, expected)
+			}
+		}
+		if err != nil {
+			t.Fatalf("step %d: operation %d: %v", step, op, err)
+		}
+		if err := Check(set, model); err != nil {
+			t.Fatalf("step %d: after operation %d: %v", step, op, err)
+		}
+	}
+}
+
+func compareOne(node *sortedset.SortedSetNode[int, int, int], entry *Entry[int, int, int]) error {
+	var nodes []*sortedset.SortedSetNode[int, int, int]
+	var entries []Entry[int, int, int]
+	if node != nil {
+		nodes = append(nodes, node)
+	}
+	if entry != nil {
+		entries = append(entries, *entry)
+	}
+	return Compare(nodes, entries)
+}
+
+func FuzzOperations(f *testing.F) {
+	f.Add([]byte{})
+	// add a few keys with tied scores, then query the edges
+	f.Add([]byte{
+		opAddOrUpdate, 1, 8, opAddOrUpdate, 2, 8, opAddOrUpdate, 3, 9, opAddOrUpdate, 4, 7,
+		opGetRangeByScore, 8, 8, 0, opGetRangeByScore, 8, 8, 1, opGetRangeByScore, 8, 8, 3,
+		opGetRangeByScore, 9, 7, 7, opGetRangeByScore, 7, 9, 13, opGetRangeByScore, 0, 15, 9,
+		opGetRangeByRank, 25, 23, 0, opGetRangeByRank, 23, 25, 0, opGetRangeByRank, 0, 47, 0,
+		opGetRangeByRank, 30, 26, 1, opGetByRank, 23, 0, opFindRank, 3, opPopMin, opPopMax,
+	})
+	// update and remove the same keys
+	f.Add([]byte{
+		opAddOrUpdate, 5, 1, opAddOrUpdate, 5, 2, opAddOrUpdate, 6, 2, opRemove, 5, opRemove, 5,
+		opFindRank, 6, opGetByRank, 22, 1, opGetByRank, 25, 1, opPopMin, opPopMax,
+	})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		runOperations(t, data)
+	})
+}
+
+func TestRandomOperations(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		data := make([]byte, 1+random.Intn(1000))
+		random.Read(data)
+		runOperations(t, data)
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package sortedsettest provides a reference model of sortedset.SortedSet for
+// testing.
+//
+// The model keeps the entries in a sorted slice and the keys in a map, so every
+// operation is simple enough to be obviously correct. A test applies the same
+// operations to a SortedSet and a Model and compares the results with Compare.
+package sortedsettest
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/wangjia184/sortedset"
+	"golang.org/x/exp/constraints"
+)
+
+// Entry is a (key, score, value) triple held by the model
+type Entry[K constraints.Ordered, SCORE constraints.Ordered, V comparable] struct {
+	Key   K
+	Score SCORE
+	Value V
+}
+
+// Model is a reference implementation of the SortedSet methods
+type Model[K constraints.Ordered, SCORE constraints.Ordered, V comparable] struct {
+	entries []Entry[K, SCORE, V] // ordered by (score, key)
+	scores  map[K]SCORE
+}
+
+// NewModel creates an empty model
+func NewModel[K constraints.Ordered, SCORE constraints.Ordered, V comparable]() *Model[K, SCORE, V] {
+	return &Model[K, SCORE, V]{scores: make(map[K]SCORE)}
+}
+
+// search returns the index of (score, key) in the entries and whether it is there
+func (this *Model[K, SCORE, V]) search(score SCORE, key K) (int, bool) {
+	return slices.BinarySearchFunc(this.entries, Entry[K, SCORE, V]{Key: key, Score: score}, func(a, b Entry[K, SCORE, V]) int {
+		if c := cmp.Compare(a.Score, b.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+}
+
+// Entries returns the entries in rank order
+func (this *Model[K, SCORE, V]) Entries() []Entry[K, SCORE, V] {
+	return slices.Clone(this.entries)
+}
+
+// GetCount returns the number of entries
+func (this *Model[K, SCORE, V]) GetCount() int {
+	return len(this.entries)
+}
+
+// AddOrUpdate inserts or updates an entry and reports whether the key was new
+func (this *Model[K, SCORE, V]) AddOrUpdate(key K, score SCORE, value V) bool {
+	old, found := this.scores[key]
+	if found {
+		i, _ := this.search(old, key)
+		this.entries = slices.Delete(this.entries, i, i+1)
+	}
+	this.scores[key] = score
+	i, _ := this.search(score, key)
+	this.entries = slices.Insert(this.entries, i, Entry[K, SCORE, V]{Key: key, Score: score, Value: value})
+	return !found
+}
+
+// Remove deletes the entry of key and returns it, nil if the key is absent
+func (this *Model[K, SCORE, V]) Remove(key K) *Entry[K, SCORE, V] {
+	score, found := this.scores[key]
+	if !found {
+		return nil
+	}
+	i, _ := this.search(score, key)
+	return this.removeAt(i)
+}
+
+func (this *Model[K, SCORE, V]) removeAt(i int) *Entry[K, SCORE, V] {
+	entry := this.entries[i]
+	this.entries = slices.Delete(this.entries, i, i+1)
+	delete(this.scores, entry.Key)
+	return &entry
+}
+
+// PopMin removes and returns the first entry, nil if the model is empty
+func (this *Model[K, SCORE, V]) PopMin() *Entry[K, SCORE, V] {
+	if len(this.entries) == 0 {
+		return nil
+	}
+	return this.removeAt(0)
+}
+
+// PopMax removes and returns the last entry, nil if the model is empty
+func (this *Model[K, SCORE, V]) PopMax() *Entry[K, SCORE, V] {
+	if len(this.entries) == 0 {
+		return nil
+	}
+	return this.removeAt(len(this.entries) - 1)
+}
+
+// FindRank returns the 1-based rank of key, 0 if the key is absent
+func (this *Model[K, SCORE, V]) FindRank(key K) int {
+	score, found := this.scores[key]
+	if !found {
+		return 0
+	}
+	i, _ := this.search(score, key)
+	return i + 1
+}
+
+// GetRangeByScore returns the entries whose score is between start and end,
+// with the semantics of SortedSet.GetRangeByScore
+func (this *Model[K, SCORE, V]) GetRangeByScore(start SCORE, end SCORE, options *sortedset.GetRangeByScoreOptions) []Entry[K, SCORE, V] {
+	var limit, excludeStart, excludeEnd = -1, false, false
+	if options != nil {
+		if options.Limit > 0 {
+			limit = options.Limit
+		}
+		excludeStart, excludeEnd = options.ExcludeStart, options.ExcludeEnd
+	}
+
+	reverse := start > end
+	min, max, excludeMin, excludeMax := start, end, excludeStart, excludeEnd
+	if reverse {
+		min, max, excludeMin, excludeMax = end, start, excludeEnd, excludeStart
+	}
+
+	var result []Entry[K, SCORE, V]
+	for i := range this.entries {
+		entry := this.entries[i]
+		if reverse {
+			entry = this.entries[len(this.entries)-1-i]
+		}
+		if entry.Score < min || entry.Score > max ||
+			(excludeMin && entry.Score == min) || (excludeMax && entry.Score == max) {
+			continue
+		}
+		if len(result) == limit {
+			break
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// GetRangeByRank returns the entries with a 1-based rank between start and
+// end, with the semantics of SortedSet.GetRangeByRank: negative ranks count
+// from the last entry, ranks below 1 become 1, and start after end reverses
+// the result. If remove is true, the returned entries are removed.
+func (this *Model[K, SCORE, V]) GetRangeByRank(start int, end int, remove bool) []Entry[K, SCORE, V] {
+	length := len(this.entries)
+	if start < 0 {
+		start += length + 1
+	}
+	if end < 0 {
+		end += length + 1
+	}
+	start, end = max(start, 1), max(end, 1)
+	reverse := start > end
+	if reverse {
+		start, end = end, start
+	}
+	if start > length {
+		return nil
+	}
+	end = min(end, length)
+
+	result := slices.Clone(this.entries[start-1 : end])
+	if remove {
+		for _, entry := range result {
+			delete(this.scores, entry.Key)
+		}
+		this.entries = slices.Delete(this.entries, start-1, end)
+	}
+	if reverse {
+		slices.Reverse(result)
+	}
+	return result
+}
+
+// Compare reports the first difference between the nodes and the entries
+// expected by the model, nil if they match
+func Compare[K constraints.Ordered, SCORE constraints.Ordered, V comparable](nodes []*sortedset.SortedSetNode[K, SCORE, V], entries []Entry[K, SCORE, V]) error {
+	if len(nodes) != len(entries) {
+		return fmt.Errorf("got %d nodes, the model has %d entries", len(nodes), len(entries))
+	}
+	for i, node := range nodes {
+		entry := entries[i]
+		if node.Key() != entry.Key || node.Score() != entry.Score || node.Value != entry.Value {
+			return fmt.Errorf("node %d is (%v, %v, %v), the model has (%v, %v, %v)",
+				i, node.Key(), node.Score(), node.Value, entry.Key, entry.Score, entry.Value)
+		}
+	}
+	return nil
+}
+
+// Check validates the structure of set and compares its whole content with
+// the model, including the rank of every key
+func Check[K constraints.Ordered, SCORE constraints.Ordered, V comparable](set *sortedset.SortedSet[K, SCORE, V], model *Model[K, SCORE, V]) error {
+	if err := set.Validate(); err != nil {
+		return err
+	}
+	if set.GetCount() != model.GetCount() {
+		return fmt.Errorf("the set has %d nodes, the model has %d entries", set.GetCount(), model.GetCount())
+	}
+	if err := Compare(set.GetRangeByRank(1, -1, false), model.entries); err != nil {
+		return err
+	}
+	for i, entry := range model.entries {
+		if rank := set.FindRank(entry.Key); rank != i+1 {
+			return fmt.Errorf("the rank of %v is %d, the model has %d", entry.Key, rank, i+1)
+		}
+	}
+	return nil
+}
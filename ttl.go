@@ -0,0 +1,286 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"cmp"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// NoExpiration is the time to live TTL returns for a member without deadline
+const NoExpiration time.Duration = -1
+
+// now returns the current time of the set clock in Unix nanoseconds
+func (this *SortedSet[K, SCORE, V]) now() int64 {
+	if pinned := atomic.LoadInt64(&this.pinned); pinned != 0 {
+		return pinned
+	}
+	if this.clock != nil {
+		return this.clock().UnixNano()
+	}
+	return time.Now().UnixNano()
+}
+
+// expiredNow returns the current time if the deadline of at least one member
+// has passed, 0 otherwise. The methods reading several members call it once,
+// then skip the members for which aliveAt reports false.
+//
+// Expired members stay linked until ExpireCycle, or a change of their key,
+// removes them, so reading the set never changes it.
+func (this *SortedSet[K, SCORE, V]) expiredNow() int64 {
+	if this.expiry == nil || this.expiry.length == 0 {
+		return 0
+	}
+	now := this.now()
+	if this.expiry.header.level[0].forward.score > now {
+		return 0
+	}
+	return now
+}
+
+// aliveAt reports whether the node has not expired at now, as returned by expiredNow
+func (this *SortedSetNode[K, SCORE, V]) aliveAt(now int64) bool {
+	return now == 0 || this.deadline == 0 || this.deadline > now
+}
+
+// expired reports whether the deadline of the node has passed
+func (this *SortedSet[K, SCORE, V]) expired(x *SortedSetNode[K, SCORE, V]) bool {
+	return x.deadline != 0 && x.deadline <= this.now()
+}
+
+// get returns the node for key, or nil if there is none or it has expired
+func (this *SortedSet[K, SCORE, V]) get(key K) *SortedSetNode[K, SCORE, V] {
+	x := this.lookup(key)
+	if x != nil && this.expired(x) {
+		return nil
+	}
+	return x
+}
+
+// access returns the node for key before changing it, or nil. An expired
+// node is removed first, so that its key is handled as a new member.
+func (this *SortedSet[K, SCORE, V]) access(key K) *SortedSetNode[K, SCORE, V] {
+	x := this.lookup(key)
+	if x != nil && this.expired(x) {
+		this.delete(x.score, x.key)
+		return nil
+	}
+	return x
+}
+
+// expiredCount returns the number of expired members at now, as returned by expiredNow
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet[K, SCORE, V]) expiredCount(now int64) int {
+	if now == 0 {
+		return 0
+	}
+	return this.expiry.countWhile(func(x *SortedSetNode[K, int64, any]) bool {
+		return x.score <= now
+	})
+}
+
+// countExpired returns the number of expired members at now satisfying fn
+//
+// Time complexity of this method is : O(D) for D expired members
+func (this *SortedSet[K, SCORE, V]) countExpired(now int64, fn func(x *SortedSetNode[K, SCORE, V]) bool) int {
+	count := 0
+	if now == 0 {
+		return count
+	}
+	for x := this.expiry.header.level[0].forward; x != nil && x.score <= now; x = x.level[0].forward {
+		if fn(x.Value.(*SortedSetNode[K, SCORE, V])) {
+			count++
+		}
+	}
+	return count
+}
+
+// liveLength returns the number of members that have not expired at now
+func (this *SortedSet[K, SCORE, V]) liveLength(now int64) int {
+	return int(this.length) - this.expiredCount(now)
+}
+
+// firstAlive returns the first node from x on, following level[0], that has
+// not expired at now
+func (this *SortedSet[K, SCORE, V]) firstAlive(x *SortedSetNode[K, SCORE, V], now int64) *SortedSetNode[K, SCORE, V] {
+	for x != nil && !x.aliveAt(now) {
+		x = x.level[0].forward
+	}
+	return x
+}
+
+// lastAlive returns the first node from x on, following the backward
+// pointers, that has not expired at now
+func (this *SortedSet[K, SCORE, V]) lastAlive(x *SortedSetNode[K, SCORE, V], now int64) *SortedSetNode[K, SCORE, V] {
+	for x != nil && !x.aliveAt(now) {
+		x = x.backward
+	}
+	return x
+}
+
+// expireDue removes at most budget members whose deadline is not after now,
+// all of them if budget is negative, and returns the number of removed members
+func (this *SortedSet[K, SCORE, V]) expireDue(now int64, budget int) int {
+	removed := 0
+	for removed != budget {
+		x := this.expiry.header.level[0].forward
+		if x == nil || x.score > now {
+			break
+		}
+		node := x.Value.(*SortedSetNode[K, SCORE, V])
+		this.delete(node.score, node.key)
+		removed++
+	}
+	return removed
+}
+
+// pin makes the set use the time now, 0 to use the clock again
+func (this *SortedSet[K, SCORE, V]) pin(now int64) {
+	atomic.StoreInt64(&this.pinned, now)
+}
+
+// setDeadline sets the deadline of node in Unix nanoseconds, 0 for none
+func (this *SortedSet[K, SCORE, V]) setDeadline(node *SortedSetNode[K, SCORE, V], deadline int64) {
+	if this.cloning(node.key) {
+		defer this.unlockClones()
+	}
+	atomic.StoreInt64(&node.deadline, deadline)
+	if deadline == 0 {
+		if this.expiry != nil {
+			this.expiry.Remove(node.key)
+		}
+		return
+	}
+	if this.expiry == nil {
+		this.expiry = NewFunc[K, int64, any](cmp.Compare[int64], this.keyCmp)
+	}
+	this.expiry.AddOrUpdate(node.key, deadline, node)
+}
+
+// deadlineAfter returns the deadline ttl after now, saturated at the maximum time
+func deadlineAfter(now int64, ttl time.Duration) int64 {
+	if int64(ttl) > math.MaxInt64-now {
+		return math.MaxInt64
+	}
+	return now + int64(ttl)
+}
+
+// AddWithTTL adds or updates an element like AddOrUpdate, and makes it expire
+// after ttl. A ttl that is not positive removes the element at once.
+// if the element is added, this method returns true; otherwise false means updated
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet[K, SCORE, V]) AddWithTTL(key K, score SCORE, value V, ttl time.Duration) bool {
+	added := this.AddOrUpdate(key, score, value)
+	this.Expire(key, ttl)
+	return added
+}
+
+// Expire sets the time to live of the element specified by key, like the
+// Redis EXPIRE command. A ttl that is not positive removes the element at once.
+//
+// It returns false if the key is not in the set.
+// The deadline is kept when the score or the value of the element is updated.
+// Deadlines are not saved by WriteTo and MarshalJSON, and the elements of a
+// copy returned by Clone do not expire.
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet[K, SCORE, V]) Expire(key K, ttl time.Duration) bool {
+	now := this.now()
+	if ttl <= 0 {
+		return this.expireAt(key, now)
+	}
+	return this.expireAt(key, deadlineAfter(now, ttl))
+}
+
+// expireAt sets the deadline of the element specified by key in Unix
+// nanoseconds, and removes it if the deadline has passed. A deadline of 0
+// removes the deadline. It returns false if the key is not in the set.
+func (this *SortedSet[K, SCORE, V]) expireAt(key K, deadline int64) bool {
+	node := this.access(key)
+	if node == nil {
+		return false
+	}
+	if deadline != 0 && deadline <= this.now() {
+		this.delete(node.score, node.key)
+		return true
+	}
+	this.setDeadline(node, deadline)
+	return true
+}
+
+// TTL returns the remaining time to live of the element specified by key, or
+// NoExpiration if the element has no deadline. It returns false if the key is
+// not in the set.
+//
+// Time complexity of this method is : O(1)
+func (this *SortedSet[K, SCORE, V]) TTL(key K) (time.Duration, bool) {
+	node := this.get(key)
+	if node == nil {
+		return 0, false
+	}
+	if node.deadline == 0 {
+		return NoExpiration, true
+	}
+	return time.Duration(node.deadline - this.now()), true
+}
+
+// Persist removes the deadline of the element specified by key, like the
+// Redis PERSIST command. It returns false if the key is not in the set or has
+// no deadline.
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet[K, SCORE, V]) Persist(key K) bool {
+	node := this.access(key)
+	if node == nil || node.deadline == 0 {
+		return false
+	}
+	this.setDeadline(node, 0)
+	return true
+}
+
+// ExpireCycle removes at most budget expired elements, the ones with the
+// earliest deadlines first, and returns the number of removed elements.
+//
+// An expired element is never returned, counted or ranked, but it stays in
+// the skip list until ExpireCycle removes it, or its key is changed. Until
+// then it takes memory, and the methods counting or ranking elements take an
+// extra O(D) time for D expired elements. The owner goroutine should call
+// ExpireCycle periodically with a small budget to bound the pause. If budget
+// is not positive, all the expired elements are removed.
+//
+// Time complexity of this method is : O(M*log(N)) for M removed elements
+func (this *SortedSet[K, SCORE, V]) ExpireCycle(budget int) int {
+	if this.expiry == nil {
+		return 0
+	}
+	if budget <= 0 {
+		budget = -1
+	}
+	return this.expireDue(this.now(), budget)
+}
@@ -0,0 +1,231 @@
+package sortedset
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock advanced by the test
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (this *fakeClock) Now() time.Time {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.now
+}
+
+func (this *fakeClock) Advance(d time.Duration) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.now = this.now.Add(d)
+}
+
+func newTTLSet() (*SortedSet[string, int, string], *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	return NewWithOptions[string, int, string](&Options{Clock: clock.Now}), clock
+}
+
+func checkKeys(t *testing.T, sortedset *SortedSet[string, int, string], expected ...string) {
+	t.Helper()
+	var keys []string
+	for key := range sortedset.All() {
+		keys = append(keys, key)
+	}
+	if len(keys) != len(expected) {
+		t.Fatalf("keys are %v, expected %v", keys, expected)
+	}
+	for i := range keys {
+		if keys[i] != expected[i] {
+			t.Fatalf("keys are %v, expected %v", keys, expected)
+		}
+	}
+	if err := sortedset.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	sortedset, clock := newTTLSet()
+	sortedset.AddOrUpdate("a", 1, "")
+	if !sortedset.AddWithTTL("b", 2, "", 10*time.Second) {
+		t.Error("AddWithTTL() of a new key returned false")
+	}
+	sortedset.AddWithTTL("c", 3, "", 20*time.Second)
+	sortedset.AddWithTTL("d", 4, "", 30*time.Second)
+	checkKeys(t, sortedset, "a", "b", "c", "d")
+
+	if ttl, ok := sortedset.TTL("a"); !ok || ttl != NoExpiration {
+		t.Errorf("TTL(a) = %v, %v", ttl, ok)
+	}
+	if ttl, ok := sortedset.TTL("b"); !ok || ttl != 10*time.Second {
+		t.Errorf("TTL(b) = %v, %v", ttl, ok)
+	}
+	if _, ok := sortedset.TTL("x"); ok {
+		t.Error("TTL() of a missing key returned true")
+	}
+
+	// the deadline survives score changes, in place or by moving the node
+	sortedset.AddOrUpdate("b", 5, "moved")
+	IncrementScore(sortedset, "c", 1)
+	if ttl, _ := sortedset.TTL("b"); ttl != 10*time.Second {
+		t.Errorf("TTL(b) after an update = %v", ttl)
+	}
+	checkKeys(t, sortedset, "a", "c", "d", "b")
+
+	clock.Advance(10 * time.Second)
+	if sortedset.Has("b") {
+		t.Error("Has() of an expired key returned true")
+	}
+	if sortedset.GetCount() != 3 || sortedset.FindRank("d") != 3 || sortedset.PeekMax().Key() != "d" {
+		t.Errorf("expired key is still counted: count %d, rank of d %d", sortedset.GetCount(), sortedset.FindRank("d"))
+	}
+	checkKeys(t, sortedset, "a", "c", "d")
+
+	// Persist and Expire
+	if !sortedset.Persist("c") || sortedset.Persist("c") || sortedset.Persist("x") {
+		t.Error("Persist() returned a wrong result")
+	}
+	if !sortedset.Expire("a", time.Second) || sortedset.Expire("x", time.Second) {
+		t.Error("Expire() returned a wrong result")
+	}
+	clock.Advance(time.Hour)
+	checkKeys(t, sortedset, "c")
+	if nodes := sortedset.GetRangeByScore(0, 10, nil); len(nodes) != 1 || nodes[0].Key() != "c" {
+		t.Errorf("GetRangeByScore() returned %d nodes", len(nodes))
+	}
+
+	// a ttl which is not positive removes the key at once
+	sortedset.Expire("c", 0)
+	sortedset.AddWithTTL("e", 1, "", -time.Second)
+	checkKeys(t, sortedset)
+}
+
+func TestExpireCycle(t *testing.T) {
+	sortedset, clock := newTTLSet()
+	if sortedset.ExpireCycle(10) != 0 {
+		t.Error("ExpireCycle() without deadline removed keys")
+	}
+	for i, key := range []string{"a", "b", "c", "d", "e"} {
+		sortedset.AddWithTTL(key, -i, "", time.Duration(i+1)*time.Second)
+	}
+	clock.Advance(3 * time.Second)
+
+	// the earliest deadlines go first, and the budget bounds the work
+	if removed := sortedset.ExpireCycle(2); removed != 2 {
+		t.Errorf("ExpireCycle(2) removed %d keys", removed)
+	}
+	if sortedset.length != 3 || sortedset.lookup("a") != nil || sortedset.lookup("c") == nil {
+		t.Errorf("ExpireCycle(2) did not remove a and b")
+	}
+	if removed := sortedset.ExpireCycle(0); removed != 1 {
+		t.Errorf("ExpireCycle(0) removed %d keys", removed)
+	}
+	if removed := sortedset.ExpireCycle(10); removed != 0 {
+		t.Errorf("ExpireCycle(10) removed %d keys", removed)
+	}
+	checkKeys(t, sortedset, "e", "d")
+
+	sortedset.Remove("d")
+	if sortedset.expiry.GetCount() != 1 {
+		t.Errorf("expiry index holds %d keys after Remove", sortedset.expiry.GetCount())
+	}
+	clock.Advance(time.Minute)
+	checkKeys(t, sortedset)
+}
+
+func TestTTLHasConcurrent(t *testing.T) {
+	sortedset, clock := newTTLSet()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			sortedset.Has("a")
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		sortedset.AddWithTTL("a", i, "", time.Second)
+		sortedset.Persist("a")
+		clock.Advance(time.Millisecond)
+	}
+	wg.Wait()
+}
+
+func checkNodeKeys(t *testing.T, nodes []*SortedSetNode[string, int, string], expected ...string) {
+	t.Helper()
+	keys := make([]string, len(nodes))
+	for i, node := range nodes {
+		keys[i] = node.Key()
+	}
+	if len(keys) != len(expected) {
+		t.Fatalf("keys are %v, expected %v", keys, expected)
+	}
+	for i := range keys {
+		if keys[i] != expected[i] {
+			t.Fatalf("keys are %v, expected %v", keys, expected)
+		}
+	}
+}
+
+func TestTTLLazyExpiry(t *testing.T) {
+	sortedset, clock := newTTLSet()
+	for i, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		if i%2 == 1 {
+			sortedset.AddWithTTL(key, i, "", time.Second)
+		} else {
+			sortedset.AddOrUpdate(key, i, "")
+		}
+	}
+	clock.Advance(time.Second)
+
+	// reads skip the expired keys without removing them
+	checkKeys(t, sortedset, "a", "c", "e", "g", "i")
+	if sortedset.GetCount() != 5 || sortedset.PeekMin().Key() != "a" || sortedset.PeekMax().Key() != "i" {
+		t.Error("GetCount()/PeekMin()/PeekMax() count expired keys")
+	}
+	if sortedset.FindRank("e") != 3 || sortedset.FindRank("b") != 0 || sortedset.GetByKey("b") != nil {
+		t.Error("FindRank()/GetByKey() count expired keys")
+	}
+	if node := sortedset.GetByRank(2, false); node == nil || node.Key() != "c" {
+		t.Error("GetByRank() does not return expected value `c`")
+	}
+	checkNodeKeys(t, sortedset.GetRangeByRank(-2, -1, false), "g", "i")
+	checkNodeKeys(t, sortedset.GetRangeByRank(4, 2, false), "g", "e", "c")
+	checkNodeKeys(t, sortedset.GetRangeByScore(0, 9, &GetRangeByScoreOptions{Limit: 2}), "a", "c")
+	checkNodeKeys(t, sortedset.GetRangeByScore(9, 0, &GetRangeByScoreOptions{Limit: 2}), "i", "g")
+	checkSeq(t, sortedset.RangeByRank(4, 2), []string{"g", "e", "c"})
+	if count := sortedset.CountByScore(1, 7, nil); count != 3 {
+		t.Errorf("CountByScore() returned %d, expected 3", count)
+	}
+	if rank := sortedset.RankOfScore(5); rank != 3 {
+		t.Errorf("RankOfScore() returned %d, expected 3", rank)
+	}
+	if cursor := sortedset.SeekRank(2); !cursor.Next() || cursor.Node().Key() != "e" || cursor.Rank() != 3 {
+		t.Error("the cursor does not skip expired keys")
+	}
+	if sortedset.length != 10 {
+		t.Errorf("reads removed expired keys, %d keys left", sortedset.length)
+	}
+
+	// a write on an expired key handles it as a new key
+	if !sortedset.AddOrUpdate("b", 1, "") {
+		t.Error("AddOrUpdate() of an expired key returned false")
+	}
+	if ttl, ok := sortedset.TTL("b"); !ok || ttl != NoExpiration {
+		t.Errorf("TTL(b) = %v, %v", ttl, ok)
+	}
+	checkNodeKeys(t, sortedset.GetRangeByRank(1, 2, true), "a", "b")
+
+	// bulk reclaim is left to ExpireCycle
+	if removed := sortedset.ExpireCycle(0); removed != 4 {
+		t.Errorf("ExpireCycle(0) removed %d keys", removed)
+	}
+	if sortedset.length != 4 {
+		t.Errorf("%d keys left after ExpireCycle()", sortedset.length)
+	}
+	checkKeys(t, sortedset, "c", "e", "g", "i")
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"cmp"
+
+	"golang.org/x/exp/constraints"
+)
+
+// SortOrder is the direction in which a tuple component is sorted
+type SortOrder int
+
+const (
+	Ascending  SortOrder = iota // lower values first
+	Descending                  // higher values first
+)
+
+func (this SortOrder) apply(c int) int {
+	if this == Descending {
+		return -c
+	}
+	return c
+}
+
+// tuplePrefix marks a tuple made by a prefix function. It stands before or
+// after every tuple sharing its first components, whatever their order.
+type tuplePrefix struct {
+	length int8 // number of significant components, 0 for a complete tuple
+	edge   int8 // -1 before or +1 after the tuples sharing the prefix
+}
+
+// at returns the edge at component i, 0 if the component is significant
+func (this tuplePrefix) at(i int) int {
+	if this.length > 0 && i >= int(this.length) {
+		return int(this.edge)
+	}
+	return 0
+}
+
+// compareEdges compares a and b at component i when one of them ends before
+// it, in which case done is true
+func compareEdges(a tuplePrefix, b tuplePrefix, i int) (c int, done bool) {
+	edgeA, edgeB := a.at(i), b.at(i)
+	if edgeA == 0 && edgeB == 0 {
+		return 0, false
+	}
+	return cmp.Compare(edgeA, edgeB), true
+}
+
+// Tuple2 is a composite score of two components
+type Tuple2[A constraints.Ordered, B constraints.Ordered] struct {
+	First  A
+	Second B
+	prefix tuplePrefix
+}
+
+// Tuple3 is a composite score of three components
+type Tuple3[A constraints.Ordered, B constraints.Ordered, C constraints.Ordered] struct {
+	First  A
+	Second B
+	Third  C
+	prefix tuplePrefix
+}
+
+// Create a Tuple2
+func NewTuple2[A constraints.Ordered, B constraints.Ordered](first A, second B) Tuple2[A, B] {
+	return Tuple2[A, B]{First: first, Second: second}
+}
+
+// Create a Tuple3
+func NewTuple3[A constraints.Ordered, B constraints.Ordered, C constraints.Ordered](first A, second B, third C) Tuple3[A, B, C] {
+	return Tuple3[A, B, C]{First: first, Second: second, Third: third}
+}
+
+// CompareTuple2 returns a comparison function for NewFunc that orders tuples
+// by First, then by Second, each in its own direction
+func CompareTuple2[A constraints.Ordered, B constraints.Ordered](first SortOrder, second SortOrder) func(a, b Tuple2[A, B]) int {
+	return func(a, b Tuple2[A, B]) int {
+		if c := first.apply(cmp.Compare(a.First, b.First)); c != 0 {
+			return c
+		}
+		if c, done := compareEdges(a.prefix, b.prefix, 1); done {
+			return c
+		}
+		return second.apply(cmp.Compare(a.Second, b.Second))
+	}
+}
+
+// CompareTuple3 returns a comparison function for NewFunc that orders tuples
+// by First, then by Second, then by Third, each in its own direction
+func CompareTuple3[A constraints.Ordered, B constraints.Ordered, C constraints.Ordered](first SortOrder, second SortOrder, third SortOrder) func(a, b Tuple3[A, B, C]) int {
+	return func(a, b Tuple3[A, B, C]) int {
+		if c := first.apply(cmp.Compare(a.First, b.First)); c != 0 {
+			return c
+		}
+		if c, done := compareEdges(a.prefix, b.prefix, 1); done {
+			return c
+		}
+		if c := second.apply(cmp.Compare(a.Second, b.Second)); c != 0 {
+			return c
+		}
+		if c, done := compareEdges(a.prefix, b.prefix, 2); done {
+			return c
+		}
+		return third.apply(cmp.Compare(a.Third, b.Third))
+	}
+}
+
+// Tuple2Prefix returns the bounds of the range of tuples whose First is first,
+// for GetRangeByScoreBound and the other score range methods
+func Tuple2Prefix[A constraints.Ordered, B constraints.Ordered](first A) (ScoreBound[Tuple2[A, B]], ScoreBound[Tuple2[A, B]]) {
+	min := Tuple2[A, B]{First: first, prefix: tuplePrefix{length: 1, edge: -1}}
+	max := Tuple2[A, B]{First: first, prefix: tuplePrefix{length: 1, edge: 1}}
+	return ScoreInclusive(min), ScoreInclusive(max)
+}
+
+// Tuple3Prefix returns the bounds of the range of tuples whose First is first,
+// for GetRangeByScoreBound and the other score range methods
+func Tuple3Prefix[A constraints.Ordered, B constraints.Ordered, C constraints.Ordered](first A) (ScoreBound[Tuple3[A, B, C]], ScoreBound[Tuple3[A, B, C]]) {
+	min := Tuple3[A, B, C]{First: first, prefix: tuplePrefix{length: 1, edge: -1}}
+	max := Tuple3[A, B, C]{First: first, prefix: tuplePrefix{length: 1, edge: 1}}
+	return ScoreInclusive(min), ScoreInclusive(max)
+}
+
+// Tuple3Prefix2 returns the bounds of the range of tuples whose First is first
+// and Second is second, for GetRangeByScoreBound and the other score range methods
+func Tuple3Prefix2[A constraints.Ordered, B constraints.Ordered, C constraints.Ordered](first A, second B) (ScoreBound[Tuple3[A, B, C]], ScoreBound[Tuple3[A, B, C]]) {
+	min := Tuple3[A, B, C]{First: first, Second: second, prefix: tuplePrefix{length: 2, edge: -1}}
+	max := Tuple3[A, B, C]{First: first, Second: second, prefix: tuplePrefix{length: 2, edge: 1}}
+	return ScoreInclusive(min), ScoreInclusive(max)
+}
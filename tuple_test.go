@@ -0,0 +1,79 @@
+package sortedset
+
+import (
+	"strings"
+	"testing"
+)
+
+type leaderboardScore = Tuple3[int, float64, int64]
+
+func checkTupleKeys(t *testing.T, nodes []*SortedSetNode[string, leaderboardScore, struct{}], expected string) {
+	t.Helper()
+	var keys []string
+	for _, node := range nodes {
+		keys = append(keys, node.Key())
+	}
+	if got := strings.Join(keys, ","); got != expected {
+		t.Errorf("keys are %q, expected %q", got, expected)
+	}
+}
+
+func TestTuple3Leaderboard(t *testing.T) {
+	// points descending, then time to finish ascending, then timestamp ascending
+	sortedset := NewFunc[string, leaderboardScore, struct{}](
+		CompareTuple3[int, float64, int64](Descending, Ascending, Ascending),
+		strings.Compare,
+	)
+	sortedset.AddOrUpdate("ann", NewTuple3(100, 31.5, int64(20)), struct{}{})
+	sortedset.AddOrUpdate("bob", NewTuple3(120, 40.0, int64(10)), struct{}{})
+	sortedset.AddOrUpdate("cid", NewTuple3(100, 31.5, int64(5)), struct{}{})
+	sortedset.AddOrUpdate("dan", NewTuple3(100, 29.0, int64(30)), struct{}{})
+	sortedset.AddOrUpdate("eve", NewTuple3(90, 10.0, int64(1)), struct{}{})
+	sortedset.AddOrUpdate("fay", NewTuple3(100, 31.5, int64(5)), struct{}{})
+
+	checkTupleKeys(t, sortedset.GetRangeByRank(1, -1, false), "bob,dan,cid,fay,ann,eve")
+
+	// all entries with points == 100
+	min, max := Tuple3Prefix[int, float64, int64](100)
+	checkTupleKeys(t, sortedset.GetRangeByScoreBound(min, max, nil), "dan,cid,fay,ann")
+	checkTupleKeys(t, sortedset.GetRangeByScoreBound(max, min, &GetRangeByScoreOptions{Limit: 2}), "ann,fay")
+	if count := sortedset.CountByScoreBound(min, max, nil); count != 4 {
+		t.Errorf("CountByScoreBound() returned %d, expected 4", count)
+	}
+
+	// exclusive prefix bounds are the same as inclusive ones
+	checkTupleKeys(t, sortedset.GetRangeByScoreBound(ScoreNegInf[leaderboardScore](), min, &GetRangeByScoreOptions{ExcludeEnd: true}), "bob")
+	checkTupleKeys(t, sortedset.GetRangeByScoreBound(max, ScorePosInf[leaderboardScore](), nil), "eve")
+
+	// entries with points == 100 and time == 31.5
+	min, max = Tuple3Prefix2[int, float64, int64](100, 31.5)
+	checkTupleKeys(t, sortedset.GetRangeByScoreBound(min, max, nil), "cid,fay,ann")
+	min, max = Tuple3Prefix2[int, float64, int64](100, 30)
+	checkTupleKeys(t, sortedset.GetRangeByScoreBound(min, max, nil), "")
+
+	min, max = Tuple3Prefix2[int, float64, int64](100, 31.5)
+	if removed := sortedset.RemoveRangeByScoreBound(min, max, nil, nil); removed != 3 {
+		t.Errorf("RemoveRangeByScoreBound() removed %d nodes, expected 3", removed)
+	}
+	checkTupleKeys(t, sortedset.GetRangeByRank(1, -1, false), "bob,dan,eve")
+}
+
+func TestTuple2(t *testing.T) {
+	sortedset := NewFunc[string, Tuple2[string, int], int](CompareTuple2[string, int](Ascending, Descending), strings.Compare)
+	sortedset.AddOrUpdate("a", NewTuple2("x", 1), 0)
+	sortedset.AddOrUpdate("b", NewTuple2("x", 3), 0)
+	sortedset.AddOrUpdate("c", NewTuple2("w", 2), 0)
+	sortedset.AddOrUpdate("d", NewTuple2("y", 0), 0)
+
+	var keys []string
+	min, max := Tuple2Prefix[string, int]("x")
+	for key := range sortedset.RangeByScoreBound(min, max, nil) {
+		keys = append(keys, key)
+	}
+	if strings.Join(keys, "") != "ba" {
+		t.Errorf("keys are %v, expected [b a]", keys)
+	}
+	if rank := sortedset.FindRank("d"); rank != 4 {
+		t.Errorf("FindRank() returned %d, expected 4", rank)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalid is wrapped by the errors returned by Validate
+var ErrInvalid = errors.New("sortedset: invalid skip list")
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvalid}, args...)...)
+}
+
+// Validate checks the structural invariants of the skip list and returns a
+// descriptive error wrapping ErrInvalid for the first violation found:
+//
+//   - nodes are in strictly ascending (score, key) order on every level
+//   - every level is a subsequence of the level below it
+//   - the spans on every level match the ranks, and sum up to the length
+//   - backward pointers and tail mirror the bottom level
+//   - level is trimmed to the highest non-empty level
+//   - the key index holds exactly the nodes of the list
+//   - the expiry index holds exactly the deadlines of the nodes
+//
+// It is meant for tests and debugging, not for normal operation.
+//
+// Time complexity of this method is : O(N)
+func (this *SortedSet[K, SCORE, V]) Validate() error {
+	if this.header == nil {
+		if this.length != 0 {
+			return invalid("length is %d without header", this.length)
+		}
+		return nil
+	}
+	if this.level < 1 || this.level > SKIPLIST_MAXLEVEL {
+		return invalid("level is %d", this.level)
+	}
+	if len(this.header.level) != SKIPLIST_MAXLEVEL {
+		return invalid("header has %d levels", len(this.header.level))
+	}
+	if this.level > 1 && this.header.level[this.level-1].forward == nil {
+		return invalid("level %d is empty but not trimmed", this.level)
+	}
+	for i := this.level; i < SKIPLIST_MAXLEVEL; i++ {
+		if this.header.level[i].forward != nil {
+			return invalid("header links level %d above level %d", i, this.level)
+		}
+	}
+
+	// bottom level: order, backward pointers, tail, length and index
+	ranks := make(map[*SortedSetNode[K, SCORE, V]]int64, this.length)
+	var tall [SKIPLIST_MAXLEVEL]int64 // tall[i] is the number of nodes with more than i levels
+	var prev *SortedSetNode[K, SCORE, V]
+	var rank int64
+	for x := this.header.level[0].forward; x != nil; x = x.level[0].forward {
+		rank++
+		if rank > this.length {
+			return invalid("more than length %d nodes on level 0", this.length)
+		}
+		if _, ok := ranks[x]; ok {
+			return invalid("level 0 loops at key %v", x.key)
+		}
+		ranks[x] = rank
+		if len(x.level) < 1 || len(x.level) > this.level {
+			return invalid("node %v has %d levels, set level is %d", x.key, len(x.level), this.level)
+		}
+		for i := range x.level {
+			tall[i]++
+		}
+		if x.backward != prev {
+			return invalid("backward pointer of node %v is wrong", x.key)
+		}
+		if prev != nil && this.compare(prev, x.score, x.key) >= 0 {
+			return invalid("node %v is not after node %v", x.key, prev.key)
+		}
+		if this.lookup(x.key) != x {
+			return invalid("index does not map key %v to its node", x.key)
+		}
+		prev = x
+	}
+	if rank != this.length {
+		return invalid("%d nodes on level 0, length is %d", rank, this.length)
+	}
+	if this.tail != prev {
+		return invalid("tail is not the last node")
+	}
+
+	indexed := 0
+	var stray error
+	this.dict.Range(func(key, value any) bool {
+		indexed++
+		if _, ok := ranks[value.(*SortedSetNode[K, SCORE, V])]; !ok {
+			stray = invalid("index maps key %v to a node not in the list", key)
+			return false
+		}
+		return true
+	})
+	if stray != nil {
+		return stray
+	}
+	if int64(indexed) != this.length {
+		return invalid("index holds %d keys, length is %d", indexed, this.length)
+	}
+
+	// upper levels: subsequence, spans and node heights
+	for i := 0; i < this.level; i++ {
+		x := this.header
+		var position int64
+		var count int64
+		for next := x.level[i].forward; next != nil; x, next = next, next.level[i].forward {
+			nextRank, ok := ranks[next]
+			if !ok {
+				return invalid("node %v on level %d is not on level 0", next.key, i)
+			}
+			if len(next.level) <= i {
+				return invalid("node %v with %d levels is linked on level %d", next.key, len(next.level), i)
+			}
+			if nextRank <= position {
+				return invalid("node %v is out of order on level %d", next.key, i)
+			}
+			if x.level[i].span != nextRank-position {
+				return invalid("span to node %v on level %d is %d, expected %d", next.key, i, x.level[i].span, nextRank-position)
+			}
+			position = nextRank
+			count++
+		}
+		if x.level[i].span != this.length-position && (x != this.header || this.length > 0) {
+			return invalid("span after the last node on level %d is %d, expected %d", i, x.level[i].span, this.length-position)
+		}
+		if count != tall[i] {
+			return invalid("%d nodes are linked on level %d, %d nodes have that level", count, i, tall[i])
+		}
+	}
+
+	// expiry index: one entry per node with a deadline
+	expiring := 0
+	for node := range ranks {
+		if node.deadline == 0 {
+			continue
+		}
+		expiring++
+		if this.expiry == nil {
+			return invalid("node %v has a deadline without expiry index", node.key)
+		}
+		if entry := this.expiry.lookup(node.key); entry == nil || entry.score != node.deadline || entry.Value != any(node) {
+			return invalid("deadline of node %v is not in the expiry index", node.key)
+		}
+	}
+	if this.expiry != nil {
+		if this.expiry.GetCount() != expiring {
+			return invalid("expiry index holds %d keys, %d nodes have a deadline", this.expiry.GetCount(), expiring)
+		}
+		if err := this.expiry.Validate(); err != nil {
+			return fmt.Errorf("expiry index: %w", err)
+		}
+	}
+	return nil
+}
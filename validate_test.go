@@ -0,0 +1,93 @@
+package sortedset
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var empty SortedSet[int, int, int]
+	if err := empty.Validate(); err != nil {
+		t.Errorf("Validate() of a zero set returned %v", err)
+	}
+
+	sortedset := NewWithOptions[int, int, int](&Options{Rand: rand.NewSource(7)})
+	random := rand.New(rand.NewSource(7))
+	for i := 0; i < 5000; i++ {
+		key := random.Intn(500)
+		switch random.Intn(8) {
+		case 0:
+			sortedset.Remove(key)
+		case 1:
+			sortedset.PopMin()
+		case 2:
+			sortedset.GetRangeByRank(-3, -1, true)
+		case 3:
+			IncrementScore(sortedset, key, random.Intn(21)-10)
+		case 4:
+			sortedset.RemoveRangeByScore(key, key+5, nil, nil)
+		default:
+			sortedset.AddOrUpdate(key, random.Intn(100), i)
+		}
+		if i%250 == 0 {
+			if err := sortedset.Validate(); err != nil {
+				t.Fatalf("Validate() after %d operations returned %v", i, err)
+			}
+		}
+	}
+	if err := sortedset.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sortedset.Clone().set.Validate(); err != nil {
+		t.Errorf("Validate() of a snapshot returned %v", err)
+	}
+	for sortedset.GetCount() > 0 {
+		sortedset.PopMax()
+	}
+	if err := sortedset.Validate(); err != nil {
+		t.Errorf("Validate() of an emptied set returned %v", err)
+	}
+}
+
+func TestValidateCorruption(t *testing.T) {
+	build := func() *SortedSet[int, int, int] {
+		sortedset := NewWithOptions[int, int, int](&Options{P: 0.5, Rand: rand.NewSource(3)})
+		for i := 0; i < 64; i++ {
+			sortedset.AddOrUpdate(i, i*10, i)
+		}
+		return sortedset
+	}
+	// the node with the most levels
+	tallest := func(sortedset *SortedSet[int, int, int]) *SortedSetNode[int, int, int] {
+		return sortedset.header.level[sortedset.level-1].forward
+	}
+
+	for _, test := range []struct {
+		expected string
+		corrupt  func(sortedset *SortedSet[int, int, int])
+	}{
+		{"is not after", func(s *SortedSet[int, int, int]) { s.GetByKey(10).score = 1000 }},
+		{"backward pointer", func(s *SortedSet[int, int, int]) { s.GetByKey(10).backward = nil }},
+		{"tail", func(s *SortedSet[int, int, int]) { s.tail = s.tail.backward }},
+		{"length", func(s *SortedSet[int, int, int]) { s.length++ }},
+		{"index does not map", func(s *SortedSet[int, int, int]) { s.dict.Delete(5) }},
+		{"not in the list", func(s *SortedSet[int, int, int]) { s.dict.Store(1000, createNode(1, 0, 1000, 0)) }},
+		{"span", func(s *SortedSet[int, int, int]) { tallest(s).level[0].span++ }},
+		{"span", func(s *SortedSet[int, int, int]) { s.header.level[s.level-1].span-- }},
+		{"not trimmed", func(s *SortedSet[int, int, int]) { s.level++ }},
+		{"above level", func(s *SortedSet[int, int, int]) { s.level-- }},
+		{"nodes are linked", func(s *SortedSet[int, int, int]) {
+			x := s.header.level[0].forward
+			x.level = append(x.level, SortedSetLevel[int, int, int]{})
+		}},
+	} {
+		sortedset := build()
+		test.corrupt(sortedset)
+		err := sortedset.Validate()
+		if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Validate() returned %v, expected an error about %q", err, test.expected)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2016, Jerry.Wang
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package sortedset
+
+import (
+	"errors"
+
+	"golang.org/x/exp/constraints"
+)
+
+// Number is the constraint of the scores that can be incremented
+type Number interface {
+	constraints.Integer | constraints.Float
+}
+
+var (
+	// ErrZAddOptions is returned by Add and AddIncr for an invalid combination of options
+	ErrZAddOptions = errors.New("sortedset: XX and NX options at the same time are not compatible; GT, LT, and NX options at the same time are not compatible")
+	// ErrScoreNaN is returned by AddIncr and IncrementScore when the resulting score is NaN
+	ErrScoreNaN = errors.New("sortedset: resulting score is not a number (NaN)")
+)
+
+// ZAddOptions mirrors the flags of the Redis ZADD command
+type ZAddOptions struct {
+	NX bool // only add new elements, never update existing ones
+	XX bool // only update existing elements, never add new ones
+	GT bool // only update existing elements if the new score is greater than the current score
+	LT bool // only update existing elements if the new score is less than the current score
+	CH bool // count the elements whose score changed in addition to the added ones
+}
+
+func (this *ZAddOptions) validate() error {
+	if this == nil {
+		return nil
+	}
+	if this.NX && this.XX {
+		return ErrZAddOptions
+	}
+	if (this.GT && this.LT) || ((this.GT || this.LT) && this.NX) {
+		return ErrZAddOptions
+	}
+	return nil
+}
+
+const (
+	zaddNone    = iota // nothing was done because of the options
+	zaddAdded          // a new element was added
+	zaddUpdated        // the score of an existing element changed
+	zaddValue          // only the value of an existing element was set
+)
+
+// zadd applies ZADD semantics for one element
+func (this *SortedSet[K, SCORE, V]) zadd(key K, score SCORE, value V, options *ZAddOptions) int {
+	var nx, xx, gt, lt bool
+	if options != nil {
+		nx, xx, gt, lt = options.NX, options.XX, options.GT, options.LT
+	}
+
+	found := this.access(key)
+	if found == nil {
+		if xx || !this.AddOrUpdate(key, score, value) {
+			return zaddNone
+		}
+		return zaddAdded
+	}
+
+	c := this.scoreCmp(score, found.score)
+	if nx || (gt && c <= 0) || (lt && c >= 0) {
+		return zaddNone
+	}
+	changed := c != 0
+	this.AddOrUpdate(key, score, value)
+	if changed {
+		return zaddUpdated
+	}
+	return zaddValue
+}
+
+// Add an element with the semantics of the Redis ZADD command.
+//
+// If options is nil, it behaves like AddOrUpdate. NX, XX, GT and LT make the
+// operation conditional; when the condition is not met neither the score nor
+// the value is changed.
+//
+// It returns the number of added elements (0 or 1), or with CH the number of
+// added elements plus elements whose score changed. Setting only the value of
+// an element is not counted as a change.
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet[K, SCORE, V]) Add(key K, score SCORE, value V, options *ZAddOptions) (int, error) {
+	if err := options.validate(); err != nil {
+		return 0, err
+	}
+	switch this.zadd(key, score, value, options) {
+	case zaddAdded:
+		return 1, nil
+	case zaddUpdated:
+		if options != nil && options.CH {
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// AddIncr adds increment to the score of the element specified by key, like
+// ZADD with the INCR option. A missing element is added with increment as its
+// score. The value of the element is set to value.
+//
+// It returns the new score and true, or false if the operation was aborted
+// because of NX, XX, GT or LT, or the element was rejected by the capacity
+// of the set. If the resulting score is NaN, for example +Inf plus -Inf,
+// nothing is changed and ErrScoreNaN is returned.
+//
+// Time complexity of this method is : O(log(N))
+func AddIncr[K comparable, SCORE Number, V any](set *SortedSet[K, SCORE, V], key K, increment SCORE, value V, options *ZAddOptions) (SCORE, bool, error) {
+	if err := options.validate(); err != nil {
+		return 0, false, err
+	}
+	score := increment
+	if found := set.access(key); found != nil {
+		score += found.score
+	}
+	if score != score {
+		return 0, false, ErrScoreNaN
+	}
+	if set.zadd(key, score, value, options) == zaddNone {
+		return 0, false, nil
+	}
+	return score, true, nil
+}
+
+// IncrementScore adds delta to the score of the element specified by key, like
+// the Redis ZINCRBY command. A missing element is added with delta as its
+// score and the zero value.
+//
+// It returns the new score and true, or false if a missing element was
+// rejected by the capacity of the set. If the resulting score is NaN, for
+// example +Inf plus -Inf, nothing is changed and ErrScoreNaN is returned.
+//
+// When the new score keeps the node between its neighbours, the node is
+// updated in place without searching the skip list.
+//
+// Time complexity of this method is : O(1) when the order does not change, otherwise O(log(N))
+func IncrementScore[K comparable, SCORE Number, V any](set *SortedSet[K, SCORE, V], key K, delta SCORE) (SCORE, bool, error) {
+	found := set.access(key)
+	if found == nil {
+		if delta != delta {
+			return 0, false, ErrScoreNaN
+		}
+		var value V
+		if set.insert(delta, key, value) == nil {
+			return 0, false, nil
+		}
+		return delta, true, nil
+	}
+	score := found.score + delta
+	if score != score {
+		return 0, false, ErrScoreNaN
+	}
+	if delta != 0 {
+		found = set.updateScore(found, score)
+	}
+	return found.score, true, nil
+}
@@ -0,0 +1,146 @@
+package sortedset
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	sortedset := New[string, int64, string]()
+
+	checkAdd := func(key string, score int64, options *ZAddOptions, expected int, expectedScore int64) {
+		t.Helper()
+		n, err := sortedset.Add(key, score, key+"-value", options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != expected {
+			t.Errorf("Add(%q, %d) returned %d, expected %d", key, score, n, expected)
+		}
+		node := sortedset.GetByKey(key)
+		if expectedScore == 0 {
+			if node != nil {
+				t.Errorf("key %q was added", key)
+			}
+		} else if node == nil || node.Score() != expectedScore {
+			t.Errorf("key %q has node %v, expected score %d", key, node, expectedScore)
+		}
+	}
+
+	checkAdd("a", 10, nil, 1, 10)
+	checkAdd("a", 20, nil, 0, 20)
+	checkAdd("a", 30, &ZAddOptions{CH: true}, 1, 30)
+	checkAdd("a", 30, &ZAddOptions{CH: true}, 0, 30)
+
+	checkAdd("b", 10, &ZAddOptions{XX: true}, 0, 0)
+	checkAdd("b", 10, &ZAddOptions{NX: true}, 1, 10)
+	checkAdd("b", 50, &ZAddOptions{NX: true, CH: true}, 0, 10)
+	checkAdd("b", 50, &ZAddOptions{XX: true, CH: true}, 1, 50)
+
+	// GT/LT only restrict updates, new elements are still added
+	checkAdd("c", 40, &ZAddOptions{GT: true}, 1, 40)
+	checkAdd("c", 35, &ZAddOptions{GT: true, CH: true}, 0, 40)
+	checkAdd("c", 45, &ZAddOptions{GT: true, CH: true}, 1, 45)
+	checkAdd("c", 50, &ZAddOptions{LT: true, CH: true}, 0, 45)
+	checkAdd("c", 5, &ZAddOptions{LT: true, XX: true, CH: true}, 1, 5)
+
+	if node := sortedset.GetByKey("b"); node.Value != "b-value" {
+		t.Errorf("value is %q", node.Value)
+	}
+	checkOrder(t, sortedset.GetRangeByRank(1, -1, false), []string{"c", "a", "b"})
+
+	for _, options := range []*ZAddOptions{
+		{NX: true, XX: true},
+		{GT: true, LT: true},
+		{NX: true, GT: true},
+		{NX: true, LT: true},
+	} {
+		if _, err := sortedset.Add("z", 1, "", options); err != ErrZAddOptions {
+			t.Errorf("Add(%+v) returned error %v", *options, err)
+		}
+	}
+}
+
+func TestAddIncr(t *testing.T) {
+	sortedset := New[string, float64, string]()
+
+	score, ok, _ := AddIncr(sortedset, "a", 1.5, "x", nil)
+	if !ok || score != 1.5 {
+		t.Errorf("AddIncr() returned %v, %v", score, ok)
+	}
+	score, ok, _ = AddIncr(sortedset, "a", 2, "y", nil)
+	if !ok || score != 3.5 || sortedset.GetByKey("a").Value != "y" {
+		t.Errorf("AddIncr() returned %v, %v", score, ok)
+	}
+	// the increment lowers the score, so GT aborts
+	if _, ok, _ = AddIncr(sortedset, "a", -1, "z", &ZAddOptions{GT: true}); ok {
+		t.Error("AddIncr() with GT applied a decrement")
+	}
+	if _, ok, _ = AddIncr(sortedset, "b", 1, "z", &ZAddOptions{XX: true}); ok || sortedset.Has("b") {
+		t.Error("AddIncr() with XX added an element")
+	}
+	if _, _, err := AddIncr(sortedset, "b", 1, "z", &ZAddOptions{NX: true, XX: true}); err != ErrZAddOptions {
+		t.Errorf("AddIncr() returned error %v", err)
+	}
+	if node := sortedset.GetByKey("a"); node.Score() != 3.5 || node.Value != "y" {
+		t.Errorf("node is %v", node)
+	}
+}
+
+func TestIncrementScore(t *testing.T) {
+	sortedset := New[string, int64, string]()
+	sortedset.AddOrUpdate("a", 10, "Kelly")
+	sortedset.AddOrUpdate("b", 20, "Staley")
+	sortedset.AddOrUpdate("c", 30, "Jordon")
+
+	// stays between its neighbours: the node is updated in place
+	node := sortedset.GetByKey("b")
+	if score, ok, _ := IncrementScore(sortedset, "b", 5); !ok || score != 25 {
+		t.Errorf("IncrementScore() returned %d, expected 25", score)
+	}
+	if sortedset.GetByKey("b") != node || node.Score() != 25 {
+		t.Error("IncrementScore() did not update the node in place")
+	}
+
+	// moves past its neighbour
+	if score, ok, _ := IncrementScore(sortedset, "a", 100); !ok || score != 110 {
+		t.Errorf("IncrementScore() returned %d, expected 110", score)
+	}
+	if sortedset.GetByKey("a").Value != "Kelly" {
+		t.Error("IncrementScore() lost the value")
+	}
+	checkOrder(t, sortedset.GetRangeByRank(1, -1, false), []string{"b", "c", "a"})
+
+	// missing elements are created
+	if score, ok, _ := IncrementScore(sortedset, "d", -5); !ok || score != -5 {
+		t.Errorf("IncrementScore() returned %d, expected -5", score)
+	}
+	checkOrder(t, sortedset.GetRangeByRank(1, -1, false), []string{"d", "b", "c", "a"})
+
+	// ties are ordered by key
+	IncrementScore(sortedset, "c", -5)
+	checkOrder(t, sortedset.GetRangeByScore(25, 25, nil), []string{"b", "c"})
+	IncrementScore(sortedset, "d", 30)
+	checkOrder(t, sortedset.GetRangeByRank(1, -1, false), []string{"b", "c", "d", "a"})
+	if rank := sortedset.FindRank("d"); rank != 3 {
+		t.Errorf("FindRank() returned %d, expected 3", rank)
+	}
+}
+
+func TestIncrementScoreNaN(t *testing.T) {
+	sortedset := New[string, float64, string]()
+	sortedset.AddOrUpdate("a", math.Inf(1), "Kelly")
+
+	if _, ok, err := IncrementScore(sortedset, "a", math.Inf(-1)); ok || err != ErrScoreNaN {
+		t.Errorf("IncrementScore() to NaN returned %v, %v", ok, err)
+	}
+	if _, ok, err := IncrementScore(sortedset, "b", math.NaN()); ok || err != ErrScoreNaN || sortedset.Has("b") {
+		t.Errorf("IncrementScore() of a missing element by NaN returned %v, %v", ok, err)
+	}
+	if _, ok, err := AddIncr(sortedset, "a", math.Inf(-1), "x", nil); ok || err != ErrScoreNaN {
+		t.Errorf("AddIncr() to NaN returned %v, %v", ok, err)
+	}
+	if node := sortedset.GetByKey("a"); !math.IsInf(node.Score(), 1) || node.Value != "Kelly" {
+		t.Error("a NaN increment changed the element")
+	}
+}